@@ -1,39 +1,170 @@
 package main
 
 import (
+	"log"
 	"time"
 
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
 	"github.com/DebroyeAntoine/go_link_vault/internal/db"
 	"github.com/DebroyeAntoine/go_link_vault/internal/handler"
 	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
 	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/oidc"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	logger.InitLogger()
+	// Hors mode dev, le serveur refuse de démarrer sans clé de signature
+	if err := auth.LoadKeys(); err != nil {
+		log.Fatal("Error loading JWT keys: ", err)
+	}
 	// Connexion DB
 	db.Connect()
+	mailer.Use(mailer.FromEnv())
+	handler.PromoteAdmins(config.List("ADMIN_EMAILS"))
+	// Les liens enregistrés avant la détection des doublons n'ont pas d'URL normalisée, et
+	// ceux enregistrés deux fois sont fusionnés avant de créer l'index unique
+	if err := handler.NormalizeLinkURLs(); err != nil {
+		logger.ErrorLogger.Println("Could not normalize the link URLs:", err)
+	} else if merged, err := handler.MergeDuplicateLinks(); err != nil {
+		logger.ErrorLogger.Println("Could not merge the duplicate links:", err)
+	} else {
+		if merged > 0 {
+			logger.InfoLogger.Printf("Merged %d duplicate links", merged)
+		}
+		if err := db.UniqueLinkURLs(); err != nil {
+			logger.ErrorLogger.Println("Could not create the unique link URL indexes:", err)
+		}
+	}
+	if cfg := oidc.ConfigFromEnv(); cfg.Issuer != "" {
+		handler.UseOIDC(oidc.NewProvider(cfg))
+	}
+	// Les liens restés dans la corbeille plus de TRASH_RETENTION sont supprimés définitivement
+	go handler.PurgeTrashEvery(config.Duration("TRASH_PURGE_INTERVAL", time.Hour))
+	// Les abonnements sont relevés toutes les FEED_POLL_INTERVAL
+	go handler.PollSubscriptionsEvery(config.Duration("FEED_POLL_INTERVAL", 30*time.Minute))
 
 	r := gin.Default()
+	// Sans proxy déclaré, X-Forwarded-For est ignoré : sinon chacun choisirait son IP pour
+	// échapper au limiteur de connexions
+	if err := r.SetTrustedProxies(config.List("TRUSTED_PROXIES")); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
+	// Les cookies de session ne sont envoyés qu'aux origines autorisées (AllowCredentials)
+	allowedOrigins := config.List("CORS_ALLOWED_ORIGINS")
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"http://localhost:5173"}
+	}
 
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
+		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", middleware.CSRFHeader, handler.SharePasswordHeader},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.GET("/.well-known/jwks.json", handler.JWKSHandler)
 	r.POST("/register", handler.RegisterUserHandler)
 	r.POST("/login", handler.LoginUserHandler)
+	r.POST("/login/2fa", handler.LoginTwoFactorHandler)
+	r.POST("/logout", handler.LogoutHandler)
+	r.GET("/auth/oidc/login", handler.OIDCLoginHandler)
+	r.GET("/auth/oidc/callback", handler.OIDCCallbackHandler)
+	r.GET("/verify-email", handler.VerifyEmailHandler)
+	r.POST("/verify-email/resend", middleware.AuthRequired(), handler.ResendVerificationHandler)
+	r.GET("/account", middleware.AuthRequired(), handler.GetAccountHandler)
+	r.PUT("/account/password", middleware.AuthRequired(), handler.ChangePasswordHandler)
+	r.PUT("/account/email", middleware.AuthRequired(), handler.ChangeEmailHandler)
+	r.DELETE("/account", middleware.AuthRequired(), handler.DeleteAccountHandler)
+	r.POST("/2fa/enroll", middleware.AuthRequired(), handler.EnrollTwoFactorHandler)
+	r.POST("/2fa/confirm", middleware.AuthRequired(), handler.ConfirmTwoFactorHandler)
+	r.POST("/2fa/disable", middleware.AuthRequired(), handler.DisableTwoFactorHandler)
+	r.POST("/2fa/recovery-codes", middleware.AuthRequired(), handler.RegenerateRecoveryCodesHandler)
 	r.POST("/links", middleware.AuthRequired(), handler.CreateLinkHandler)
 	r.GET("/links", middleware.AuthRequired(), handler.GetLinksHandler)
+	r.GET("/links/duplicates", middleware.AuthRequired(), handler.GetDuplicatesHandler)
+	r.POST("/links/merge", middleware.AuthRequired(), handler.MergeLinksHandler)
+	r.POST("/links/bulk", middleware.AuthRequired(), handler.BulkLinksHandler)
+	r.POST("/links/batch", middleware.AuthRequired(), handler.CreateLinksBatchHandler)
 	r.PUT("/link/:id", middleware.AuthRequired(), handler.UpdateLinkHandler)
 	r.DELETE("link/:id", middleware.AuthRequired(), handler.DeleteLinkHandler)
 	r.GET("link/:id", middleware.AuthRequired(), handler.GetLinkHandler)
+	r.GET("/link/:id/comments", middleware.AuthRequired(), handler.GetCommentsHandler)
+	r.POST("/link/:id/comments", middleware.AuthRequired(), handler.CreateCommentHandler)
+	r.PUT("/link/:id/comments/:comment_id", middleware.AuthRequired(), handler.UpdateCommentHandler)
+	r.DELETE("/link/:id/comments/:comment_id", middleware.AuthRequired(), handler.DeleteCommentHandler)
+	r.GET("/link/:id/revisions", middleware.AuthRequired(), handler.GetRevisionsHandler)
+	r.GET("/link/:id/revisions/diff", middleware.AuthRequired(), handler.DiffRevisionsHandler)
+	r.POST("/link/:id/revisions/:revision_id/restore", middleware.AuthRequired(), handler.RestoreRevisionHandler)
+	r.POST("/link/:id/restore", middleware.AuthRequired(), handler.RestoreLinkHandler)
+	r.GET("/trash", middleware.AuthRequired(), handler.GetTrashHandler)
+	r.POST("/import/netscape", middleware.AuthRequired(), handler.ImportNetscapeHandler)
+	r.POST("/import/archive", middleware.AuthRequired(), handler.ImportArchiveHandler)
+	r.POST("/import/:format", middleware.AuthRequired(), handler.ImportHandler)
+	r.GET("/import/jobs", middleware.AuthRequired(), handler.GetImportJobsHandler)
+	r.GET("/import/jobs/:id", middleware.AuthRequired(), handler.GetImportJobHandler)
+	r.GET("/export", middleware.AuthRequired(), handler.ExportArchiveHandler)
+	r.GET("/export/netscape", middleware.AuthRequired(), handler.ExportNetscapeHandler)
+	r.DELETE("/trash", middleware.AuthRequired(), handler.EmptyTrashHandler)
+	r.DELETE("/trash/:id", middleware.AuthRequired(), handler.PurgeLinkHandler)
+	r.POST("/collections", middleware.AuthRequired(), handler.CreateCollectionHandler)
+	r.GET("/collections", middleware.AuthRequired(), handler.GetCollectionsHandler)
+	r.GET("/collections/:id", middleware.AuthRequired(), handler.GetCollectionHandler)
+	r.PUT("/collections/:id", middleware.AuthRequired(), handler.UpdateCollectionHandler)
+	r.DELETE("/collections/:id", middleware.AuthRequired(), handler.DeleteCollectionHandler)
+	r.POST("/shares", middleware.AuthRequired(), middleware.VerifiedRequired(), handler.CreateShareHandler)
+	r.GET("/shares", middleware.AuthRequired(), handler.GetSharesHandler)
+	r.DELETE("/shares/:id", middleware.AuthRequired(), handler.DeleteShareHandler)
+	r.GET("/s/:token", handler.PublicShareHandler)
+	r.POST("/feed-tokens", middleware.AuthRequired(), handler.CreateFeedTokenHandler)
+	r.GET("/feed-tokens", middleware.AuthRequired(), handler.GetFeedTokensHandler)
+	r.DELETE("/feed-tokens/:id", middleware.AuthRequired(), handler.DeleteFeedTokenHandler)
+	r.GET("/feeds/:token/users/:user_id", handler.UserFeedHandler)
+	r.GET("/feeds/:token/tags/:tag", handler.TagFeedHandler)
+	r.GET("/feeds/:token/collections/:id", handler.CollectionFeedHandler)
+	r.POST("/subscriptions", middleware.AuthRequired(), handler.CreateSubscriptionHandler)
+	r.GET("/subscriptions", middleware.AuthRequired(), handler.GetSubscriptionsHandler)
+	r.GET("/subscriptions/:id", middleware.AuthRequired(), handler.GetSubscriptionHandler)
+	r.PUT("/subscriptions/:id", middleware.AuthRequired(), handler.UpdateSubscriptionHandler)
+	r.DELETE("/subscriptions/:id", middleware.AuthRequired(), handler.DeleteSubscriptionHandler)
+	r.POST("/subscriptions/:id/poll", middleware.AuthRequired(), handler.PollSubscriptionHandler)
+	r.POST("/grants", middleware.AuthRequired(), middleware.VerifiedRequired(), handler.CreateGrantHandler)
+	r.GET("/grants", middleware.AuthRequired(), handler.GetGrantsHandler)
+	r.PUT("/grants/:id", middleware.AuthRequired(), handler.UpdateGrantHandler)
+	r.DELETE("/grants/:id", middleware.AuthRequired(), handler.DeleteGrantHandler)
+	r.GET("/shared-with-me", middleware.AuthRequired(), handler.SharedWithMeHandler)
+	r.GET("/activity", middleware.AuthRequired(), handler.ActivityHandler)
+	r.POST("/workspaces", middleware.AuthRequired(), handler.CreateWorkspaceHandler)
+	r.GET("/workspaces", middleware.AuthRequired(), handler.GetWorkspacesHandler)
+	r.GET("/workspaces/:id", middleware.AuthRequired(), handler.GetWorkspaceHandler)
+	r.PUT("/workspaces/:id", middleware.AuthRequired(), handler.UpdateWorkspaceHandler)
+	r.DELETE("/workspaces/:id", middleware.AuthRequired(), handler.DeleteWorkspaceHandler)
+	r.POST("/workspaces/:id/invitations", middleware.AuthRequired(), middleware.VerifiedRequired(), handler.InviteMemberHandler)
+	r.GET("/workspaces/:id/invitations", middleware.AuthRequired(), handler.GetInvitationsHandler)
+	r.DELETE("/workspaces/:id/invitations/:invitation_id", middleware.AuthRequired(), handler.DeleteInvitationHandler)
+	r.PUT("/workspaces/:id/members/:user_id", middleware.AuthRequired(), handler.SetMemberRoleHandler)
+	r.DELETE("/workspaces/:id/members/:user_id", middleware.AuthRequired(), handler.RemoveMemberHandler)
+	r.POST("/invitations/accept", middleware.AuthRequired(), handler.AcceptInvitationHandler)
+
+	admin := r.Group("/admin", middleware.AuthRequired(), middleware.AdminRequired())
+	admin.GET("/users", handler.ListUsersHandler)
+	admin.GET("/users/:id", handler.GetUserHandler)
+	admin.POST("/users/:id/disable", handler.DisableUserHandler)
+	admin.POST("/users/:id/enable", handler.EnableUserHandler)
+	admin.POST("/users/:id/reset-password", handler.ResetPasswordHandler)
+	admin.PUT("/users/:id/role", handler.SetRoleHandler)
+	admin.DELETE("/users/:id", handler.DeleteUserHandler)
+	admin.POST("/scrape/refresh", handler.RefreshScrapingHandler)
+	admin.GET("/lockouts", handler.ListLockoutsHandler)
+	admin.POST("/unlock", handler.UnlockLoginHandler)
+	admin.GET("/audit", handler.AuditLogHandler)
 
 	r.Run(":8080")
 }
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+)
+
+// Record appends an event to the audit log. Failures are logged but never block the request.
+func Record(event models.AuditEvent, details interface{}) {
+	if details != nil {
+		if raw, err := json.Marshal(details); err == nil {
+			event.Details = raw
+		}
+	}
+
+	if err := db.DB.Create(&event).Error; err != nil && logger.ErrorLogger != nil {
+		logger.ErrorLogger.Println("Failed to record audit event:", err)
+	}
+}
+
+// Change records the modification of a target with the fields that differ between the
+// before and after snapshots. before is nil for a creation and after is nil for a deletion.
+// Nothing is recorded when an update changes nothing.
+func Change(event models.AuditEvent, before, after interface{}) {
+	oldValues, newValues := Diff(before, after)
+	if before != nil && after != nil && len(newValues) == 0 {
+		return
+	}
+
+	if oldValues != nil {
+		event.Before, _ = json.Marshal(oldValues)
+	}
+	if newValues != nil {
+		event.After, _ = json.Marshal(newValues)
+	}
+	Record(event, nil)
+}
+
+// Diff compares the JSON representation of two snapshots and returns the old and new
+// values of the fields that differ. A nil snapshot gives a nil map.
+func Diff(before, after interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldFields, newFields := fields(before), fields(after)
+	if oldFields == nil || newFields == nil {
+		return oldFields, newFields
+	}
+
+	oldValues := map[string]interface{}{}
+	newValues := map[string]interface{}{}
+	for key, value := range newFields {
+		if previous, ok := oldFields[key]; !ok || !reflect.DeepEqual(previous, value) {
+			oldValues[key] = oldFields[key]
+			newValues[key] = value
+		}
+	}
+	for key, value := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			oldValues[key] = value
+			newValues[key] = nil
+		}
+	}
+	return oldValues, newValues
+}
+
+// fields decodes a snapshot as a JSON object
+func fields(snapshot interface{}) map[string]interface{} {
+	if snapshot == nil || reflect.ValueOf(snapshot).Kind() == reflect.Ptr && reflect.ValueOf(snapshot).IsNil() {
+		return nil
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	var values map[string]interface{}
+	if json.Unmarshal(raw, &values) != nil {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"url": "https://go.dev", "title": "Go", "tags": []string{"go"}}
+	after := map[string]interface{}{"url": "https://go.dev", "title": "The Go language", "tags": []string{"go", "lang"}}
+
+	oldValues, newValues := Diff(before, after)
+	assert.Equal(t, map[string]interface{}{"title": "Go", "tags": []interface{}{"go"}}, oldValues)
+	assert.Equal(t, map[string]interface{}{"title": "The Go language", "tags": []interface{}{"go", "lang"}}, newValues)
+
+	// Création et suppression : tout l'état d'un seul côté
+	oldValues, newValues = Diff(nil, after)
+	assert.Nil(t, oldValues)
+	assert.Equal(t, "The Go language", newValues["title"])
+
+	oldValues, newValues = Diff(before, nil)
+	assert.Equal(t, "Go", oldValues["title"])
+	assert.Nil(t, newValues)
+
+	oldValues, newValues = Diff(before, before)
+	assert.Empty(t, oldValues)
+	assert.Empty(t, newValues)
+}
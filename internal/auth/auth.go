@@ -8,35 +8,43 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"errors"
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-var jwtKey []byte
-
-func init() {
-	key := os.Getenv("JWT_SECRET_KEY")
-	if key == "" {
-		key = "dev_default_secret" // à utiliser seulement en dev
-	}
-	jwtKey = []byte(key)
-}
+// Secret HS256 (JWT_SECRET_KEY), "dev_default_secret" tant que LoadKeys n'a pas été appelé
+var jwtKey = currentKeyring().Secret
 
 func JwtKey() []byte {
 	return jwtKey
 }
 
+// Claims of the session tokens. Issuer holds the email and Subject the user ID.
+type Claims struct {
+	jwt.StandardClaims
+	// Must match models.User.SessionVersion, otherwise the session was revoked
+	SessionVersion int `json:"sv,omitempty"`
+}
+
+// TokenLifetime is the validity of the session tokens
+const TokenLifetime = 24 * time.Hour
+
 // Create JWT Token
 func CreateToken(user models.User) (string, error) {
-	claims := &jwt.StandardClaims{
-		ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-		Issuer:    user.Email,
+	now := time.Now()
+	claims := &Claims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: now.Add(TokenLifetime).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    user.Email,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		},
+		SessionVersion: user.SessionVersion,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := currentKeyring().sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -58,7 +66,16 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func ValidateToken(c *gin.Context) (*jwt.StandardClaims, error) {
+// Hash comparé quand l'utilisateur n'existe pas, calculé une fois au démarrage
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// CheckDummyPassword spends the same time as CheckPasswordHash when the user does not exist,
+// so unknown emails can't be told apart from wrong passwords by timing
+func CheckDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func ValidateToken(c *gin.Context) (*Claims, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		return nil, errors.New("missing authorization header")
@@ -68,20 +85,13 @@ func ValidateToken(c *gin.Context) (*jwt.StandardClaims, error) {
 	tokenString := strings.Split(authHeader, " ")[1]
 
 	// Parse le token
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
-		return nil, errors.New("invalid token")
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
 	// Récupère les claims (informations utilisateur)
-	claims, ok := token.Claims.(*jwt.StandardClaims)
 	c.Set("userEmail", claims.Issuer)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
 
 	return claims, nil
 }
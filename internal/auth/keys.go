@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/jwk"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const devSecret = "dev_default_secret"
+
+// SigningKey is a key used to sign or only verify the session tokens
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{} // nil pour une clé retirée, qui ne sert plus qu'à vérifier
+	Public  interface{}
+}
+
+// Keyring holds the active signing key and the keys still accepted for verification
+type Keyring struct {
+	Active *SigningKey
+	Keys   map[string]*SigningKey
+	// Secret HS256 des tokens sans kid (JWT_SECRET_KEY), vide si désactivé
+	Secret []byte
+}
+
+var (
+	keyringMu sync.RWMutex
+	keyring   = legacyKeyring([]byte(config.String("JWT_SECRET_KEY", devSecret)))
+)
+
+// legacyKeyring signs with a single HS256 secret, without kid
+func legacyKeyring(secret []byte) *Keyring {
+	return &Keyring{
+		Active: &SigningKey{Method: jwt.SigningMethodHS256, Private: secret, Public: secret},
+		Keys:   map[string]*SigningKey{},
+		Secret: secret,
+	}
+}
+
+func currentKeyring() *Keyring {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	return keyring
+}
+
+// UseKeyring replaces the keys used by CreateToken and ParseToken
+func UseKeyring(k *Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyring = k
+	if secret := k.Secret; secret != nil {
+		jwtKey = secret
+	}
+}
+
+// IsDevMode reports whether APP_ENV is "dev" or "development"
+func IsDevMode() bool {
+	switch strings.ToLower(config.String("APP_ENV", "")) {
+	case "dev", "development":
+		return true
+	}
+	return false
+}
+
+// LoadKeys builds the keyring from the environment, to be called at startup:
+//   - JWT_KEY_FILES: comma separated "kid=path" PEM files (RSA, EC P-256 or Ed25519).
+//     Private keys sign and verify, public keys only verify (rotated out keys).
+//   - JWT_ACTIVE_KEY_ID: kid signing new tokens, the first private key by default.
+//   - JWT_SECRET_KEY: HS256 secret, used to sign when no file is configured and
+//     still accepted for tokens without kid.
+//
+// Without any key, it fails unless APP_ENV is dev, where the dev secret is used.
+func LoadKeys() error {
+	k := &Keyring{Keys: map[string]*SigningKey{}}
+	if secret := config.String("JWT_SECRET_KEY", ""); secret != "" {
+		k.Secret = []byte(secret)
+	}
+
+	var firstPrivate *SigningKey
+	for _, entry := range config.List("JWT_KEY_FILES") {
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return fmt.Errorf("JWT_KEY_FILES: invalid entry %q, expected kid=path", entry)
+		}
+		if _, exists := k.Keys[kid]; exists {
+			return fmt.Errorf("JWT_KEY_FILES: duplicate kid %q", kid)
+		}
+		key, err := LoadKeyFile(kid, path)
+		if err != nil {
+			return err
+		}
+		k.Keys[kid] = key
+		if firstPrivate == nil && key.Private != nil {
+			firstPrivate = key
+		}
+	}
+
+	if activeID := config.String("JWT_ACTIVE_KEY_ID", ""); activeID != "" {
+		key, ok := k.Keys[activeID]
+		if !ok || key.Private == nil {
+			return fmt.Errorf("JWT_ACTIVE_KEY_ID: no private key %q", activeID)
+		}
+		k.Active = key
+	} else {
+		k.Active = firstPrivate
+	}
+
+	if k.Active == nil && k.Secret != nil {
+		k.Active = &SigningKey{Method: jwt.SigningMethodHS256, Private: k.Secret, Public: k.Secret}
+	}
+	if k.Active == nil {
+		if !IsDevMode() {
+			return errors.New("no JWT signing key configured: set JWT_KEY_FILES or JWT_SECRET_KEY (or APP_ENV=dev)")
+		}
+		k = legacyKeyring([]byte(devSecret))
+	}
+
+	UseKeyring(k)
+	return nil
+}
+
+// LoadKeyFile reads a PEM private or public key
+func LoadKeyFile(kid, path string) (*SigningKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key %q: %w", kid, err)
+	}
+	key, err := ParseKeyPEM(kid, raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing key %q: %w", kid, err)
+	}
+	return key, nil
+}
+
+// ParseKeyPEM parses PKCS#8, PKCS#1 or SEC1 private keys, and PKIX public keys
+func ParseKeyPEM(kid string, raw []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(kid, parsed)
+}
+
+func newSigningKey(kid string, parsed interface{}) (*SigningKey, error) {
+	key := &SigningKey{ID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = parsed
+		parsed = signer.Public()
+	}
+
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = SigningMethodEd25519
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	key.Public = parsed
+	return key, nil
+}
+
+// keyFor returns the verification key of a token, refusing algorithms that don't match the key
+func (k *Keyring) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if k.Secret == nil {
+			return nil, errors.New("token without kid")
+		}
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return k.Secret, nil
+	}
+
+	key, ok := k.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// sign signs the claims with the active key
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.Active.Method, claims)
+	if k.Active.ID != "" {
+		token.Header["kid"] = k.Active.ID
+	}
+	return token.SignedString(k.Active.Private)
+}
+
+// ParseToken verifies a session token with the keyring and returns its claims
+func ParseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, currentKeyring().keyFor)
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys other services can use to validate our tokens.
+// The HS256 secret is never published.
+func JWKS() jwk.Set {
+	set := jwk.Set{Keys: []jwk.Key{}}
+	for _, key := range currentKeyring().Keys {
+		k, err := jwk.FromPublicKey(key.Public, key.ID, key.Method.Alg())
+		if err == nil {
+			set.Keys = append(set.Keys, k)
+		}
+	}
+	return set
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKey écrit la clé privée (ou seulement la clé publique) au format PEM
+func writeKey(t *testing.T, dir, name string, priv interface{}, publicOnly bool) string {
+	var block *pem.Block
+	if publicOnly {
+		der, err := x509.MarshalPKIXPublicKey(priv.(crypto.Signer).Public())
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(dir, name+".pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	return path
+}
+
+// restoreKeyring remet le trousseau par défaut après le test
+func restoreKeyring(t *testing.T) {
+	previous := currentKeyring()
+	t.Cleanup(func() { UseKeyring(previous) })
+}
+
+func TestLoadKeysRS256AndEdDSA(t *testing.T) {
+	restoreKeyring(t)
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	t.Setenv("JWT_KEY_FILES", "ed-1="+writeKey(t, dir, "ed", edKey, false)+",rsa-1="+writeKey(t, dir, "rsa", rsaKey, false))
+	t.Setenv("JWT_ACTIVE_KEY_ID", "rsa-1")
+	t.Setenv("JWT_SECRET_KEY", "")
+
+	assert.NoError(t, LoadKeys())
+
+	token, err := CreateToken(models.User{Email: "keys@example.com"})
+	assert.NoError(t, err)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Header["alg"])
+	assert.Equal(t, "rsa-1", parsed.Header["kid"])
+
+	claims, err := ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "keys@example.com", claims.Issuer)
+
+	// Les deux clés sont publiées, aucun secret HS256
+	set := JWKS()
+	assert.Len(t, set.Keys, 2)
+	okp, ok := set.Find("ed-1")
+	assert.True(t, ok)
+	assert.Equal(t, "EdDSA", okp.Alg)
+
+	// Signature EdDSA
+	t.Setenv("JWT_ACTIVE_KEY_ID", "ed-1")
+	assert.NoError(t, LoadKeys())
+	token, err = CreateToken(models.User{Email: "keys@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJhbGciOiJFZERTQSIs", token[:20]) // {"alg":"EdDSA",
+	_, err = ParseToken(token)
+	assert.NoError(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	restoreKeyring(t)
+	dir := t.TempDir()
+	t.Setenv("JWT_SECRET_KEY", "")
+	t.Setenv("JWT_ACTIVE_KEY_ID", "")
+
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	t.Setenv("JWT_KEY_FILES", "old="+writeKey(t, dir, "old", oldKey, false))
+	assert.NoError(t, LoadKeys())
+	oldToken, _ := CreateToken(models.User{Email: "rotation@example.com"})
+
+	// La nouvelle clé signe, l'ancienne ne sert plus qu'à vérifier
+	t.Setenv("JWT_KEY_FILES", "new="+writeKey(t, dir, "new", newKey, false)+",old="+writeKey(t, dir, "old-pub", oldKey, true))
+	assert.NoError(t, LoadKeys())
+
+	newToken, _ := CreateToken(models.User{Email: "rotation@example.com"})
+	parsed, _, _ := new(jwt.Parser).ParseUnverified(newToken, &Claims{})
+	assert.Equal(t, "new", parsed.Header["kid"])
+
+	_, err := ParseToken(oldToken)
+	assert.NoError(t, err)
+
+	// Une fois l'ancienne clé retirée, ses tokens sont refusés
+	t.Setenv("JWT_KEY_FILES", "new="+filepath.Join(dir, "new.pem"))
+	assert.NoError(t, LoadKeys())
+	_, err = ParseToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestParseTokenRejectsAlgorithmConfusion(t *testing.T) {
+	restoreKeyring(t)
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	t.Setenv("JWT_KEY_FILES", "rsa-1="+writeKey(t, dir, "rsa", rsaKey, false))
+	t.Setenv("JWT_SECRET_KEY", "")
+	t.Setenv("JWT_ACTIVE_KEY_ID", "")
+	assert.NoError(t, LoadKeys())
+
+	// Token HS256 signé avec la clé publique en guise de secret
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{})
+	forged.Header["kid"] = "rsa-1"
+	tokenString, _ := forged.SignedString(pubDER)
+
+	_, err := ParseToken(tokenString)
+	assert.Error(t, err)
+}
+
+func TestLoadKeysRequiresKeyOutsideDev(t *testing.T) {
+	restoreKeyring(t)
+	t.Setenv("JWT_KEY_FILES", "")
+	t.Setenv("JWT_SECRET_KEY", "")
+
+	t.Setenv("APP_ENV", "production")
+	err := LoadKeys()
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no JWT signing key"))
+
+	t.Setenv("APP_ENV", "dev")
+	assert.NoError(t, LoadKeys())
+	_, err = CreateToken(models.User{Email: "dev@example.com"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomToken(t *testing.T) {
+	a, err := RandomToken(32)
+	assert.NoError(t, err)
+	b, err := RandomToken(32)
+	assert.NoError(t, err)
+
+	assert.Len(t, a, 43) // 32 octets en base64 sans padding
+	assert.NotEqual(t, a, b)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, HashToken("abc"), HashToken("abc"))
+	assert.NotEqual(t, HashToken("abc"), HashToken("abd"))
+	assert.Len(t, HashToken("abc"), 64)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Secret de la RFC 6238 ("12345678901234567890") encodé en base32
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	// Vecteurs SHA1 de l'annexe B de la RFC 6238, tronqués à 6 chiffres
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range vectors {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(ts, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "timestamp %d", ts)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, _ := TOTPCode(secret, TOTPStep(now))
+
+	step, ok := ValidateTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	// Le code précédent reste accepté (décalage d'horloge)
+	_, ok = ValidateTOTP(secret, code, now.Add(30*time.Second))
+	assert.True(t, ok)
+
+	// Mais pas au-delà de la fenêtre
+	_, ok = ValidateTOTP(secret, code, now.Add(2*time.Minute))
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("ABCDEF", "user@example.com", "Go Link Vault")
+
+	u, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Go Link Vault:user@example.com", u.Path)
+	assert.Equal(t, "ABCDEF", u.Query().Get("secret"))
+	assert.Equal(t, "Go Link Vault", u.Query().Get("issuer"))
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the environment variable or the fallback when unset
+func String(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Bool parses the environment variable as a boolean ("true", "1", "yes"...)
+func Bool(key string, fallback bool) bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	switch value {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	return fallback
+}
+
+// Int parses the environment variable as an integer
+func Int(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Duration parses the environment variable with time.ParseDuration ("15m", "48h"...)
+func Duration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// List splits a comma separated environment variable, ignoring empty entries
+func List(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// BaseURL is the public URL of the API, used to build links sent by email
+func BaseURL() string {
+	return strings.TrimRight(String("APP_BASE_URL", "http://localhost:8080"), "/")
+}
+
+// FrontendURL is the URL of the web front-end, used for links that must be opened in the app
+func FrontendURL() string {
+	return strings.TrimRight(String("APP_FRONTEND_URL", "http://localhost:5173"), "/")
+}
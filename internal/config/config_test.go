@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	t.Setenv("CONFIG_TEST_STRING", "")
+	assert.Equal(t, "fallback", String("CONFIG_TEST_STRING", "fallback"))
+
+	t.Setenv("CONFIG_TEST_STRING", "value")
+	assert.Equal(t, "value", String("CONFIG_TEST_STRING", "fallback"))
+}
+
+func TestBool(t *testing.T) {
+	t.Setenv("CONFIG_TEST_BOOL", "yes")
+	assert.True(t, Bool("CONFIG_TEST_BOOL", false))
+
+	t.Setenv("CONFIG_TEST_BOOL", "0")
+	assert.False(t, Bool("CONFIG_TEST_BOOL", true))
+
+	// Valeur invalide : on garde la valeur par défaut
+	t.Setenv("CONFIG_TEST_BOOL", "maybe")
+	assert.True(t, Bool("CONFIG_TEST_BOOL", true))
+}
+
+func TestIntAndDuration(t *testing.T) {
+	t.Setenv("CONFIG_TEST_INT", "42")
+	assert.Equal(t, 42, Int("CONFIG_TEST_INT", 1))
+
+	t.Setenv("CONFIG_TEST_INT", "abc")
+	assert.Equal(t, 1, Int("CONFIG_TEST_INT", 1))
+
+	t.Setenv("CONFIG_TEST_DURATION", "15m")
+	assert.Equal(t, 15*time.Minute, Duration("CONFIG_TEST_DURATION", time.Hour))
+}
+
+func TestList(t *testing.T) {
+	t.Setenv("CONFIG_TEST_LIST", " a, b ,,c ")
+	assert.Equal(t, []string{"a", "b", "c"}, List("CONFIG_TEST_LIST"))
+}
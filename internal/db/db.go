@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/DebroyeAntoine/go_link_vault/internal/models"
 	"github.com/joho/godotenv"
@@ -13,6 +14,63 @@ import (
 
 var DB *gorm.DB
 
+// migrate crée ou met à jour toutes les tables de l'application
+func migrate() error {
+	return DB.AutoMigrate(
+		&models.User{},
+		&models.Link{},
+		&models.EmailVerification{},
+		&models.RecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.LoginThrottle{},
+		&models.AuditEvent{},
+		&models.ExternalIdentity{},
+		&models.OIDCLoginState{},
+		&models.Collection{},
+		&models.Share{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.WorkspaceInvitation{},
+		&models.Grant{},
+		&models.Comment{},
+		&models.CommentMention{},
+		&models.LinkRevision{},
+		&models.ImportJob{},
+		&models.FeedToken{},
+		&models.Subscription{},
+		&models.SubscriptionEntry{},
+	)
+}
+
+// UniqueLinkURLs enforces one live link per normalized URL in each library, the personal
+// links of a user or the links of a workspace. The links in the trash are not concerned.
+// The URLs must be normalized and the duplicates merged first, see handler.NormalizeLinkURLs.
+func UniqueLinkURLs() error {
+	for _, index := range []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_links_personal_url ON links (user_id, normalized_url) WHERE workspace_id IS NULL AND deleted_at IS NULL",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_links_workspace_url ON links (workspace_id, normalized_url) WHERE workspace_id IS NOT NULL AND deleted_at IS NULL",
+	} {
+		if err := DB.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UniqueUserEmails enforces one account per email, ignoring case. Accounts registered twice
+// before the check are reported and the index is not created until they are sorted out.
+func UniqueUserEmails() error {
+	var collisions []string
+	if err := DB.Unscoped().Model(&models.User{}).Group("lower(email)").Having("COUNT(*) > 1").
+		Pluck("lower(email)", &collisions).Error; err != nil {
+		return err
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("emails used by several accounts: %s", strings.Join(collisions, ", "))
+	}
+	return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email))").Error
+}
+
 // Fonction pour connecter à la base de données
 func Connect() {
 	// Charger le fichier .env
@@ -40,10 +98,14 @@ func Connect() {
 	}
 
 	// Auto-migrate pour créer les tables si elles n'existent pas
-	err = DB.AutoMigrate(&models.User{}, &models.Link{})
+	err = migrate()
 	if err != nil {
 		log.Fatal("Error migrating database: ", err)
 	}
+	// Le serveur démarre quand même, les comptes en double sont à fusionner à la main
+	if err := UniqueUserEmails(); err != nil {
+		log.Println("Could not enforce unique emails:", err)
+	}
 }
 
 // Fonction utilitaire pour configurer une base de données de test
@@ -74,10 +136,16 @@ func SetupTestDB() {
 	}
 
 	// Supprimer la table existante si elle existe
-	DB.Exec("TRUNCATE TABLE links, users RESTART IDENTITY CASCADE")
+	DB.Exec("TRUNCATE TABLE links, users, email_verifications, recovery_codes, two_factor_challenges, login_throttles, audit_events, external_identities, oidc_login_states, collections, shares, workspaces, workspace_members, workspace_invitations, grants, comments, comment_mentions, link_revisions, import_jobs, feed_tokens, subscriptions, subscription_entries RESTART IDENTITY CASCADE")
 
-	err = DB.AutoMigrate(&models.User{}, &models.Link{})
+	err = migrate()
 	if err != nil {
 		log.Fatal("Error migrating database: ", err)
 	}
+	if err := UniqueLinkURLs(); err != nil {
+		log.Fatal("Error creating the link indexes: ", err)
+	}
+	if err := UniqueUserEmails(); err != nil {
+		log.Fatal("Error creating the email index: ", err)
+	}
 }
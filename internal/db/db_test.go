@@ -29,6 +29,21 @@ func TestConnect(t *testing.T) {
 	assert.Equal(t, len(links), 0, "Newly created links table should be empty")
 }
 
+func TestUniqueUserEmailsReportsCollisions(t *testing.T) {
+	SetupTestDB()
+
+	// Comptes inscrits deux fois avant la vérification
+	assert.NoError(t, DB.Exec("DROP INDEX idx_users_email_lower").Error)
+	assert.NoError(t, DB.Create(&models.User{Email: "Twice@example.com", Password: "x"}).Error)
+	assert.NoError(t, DB.Create(&models.User{Email: "twice@example.com", Password: "x"}).Error)
+
+	err := UniqueUserEmails()
+	assert.ErrorContains(t, err, "twice@example.com")
+
+	assert.NoError(t, DB.Unscoped().Where("email = ?", "Twice@example.com").Delete(&models.User{}).Error)
+	assert.NoError(t, UniqueUserEmails())
+}
+
 func TestConnectFail(t *testing.T) {
 	// Intentionally breaking the connection to test failure
 	dsn := "host=invalid_host user=invalid_user password=invalid dbname=invalid port=5432 sslmode=disable"
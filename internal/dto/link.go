@@ -4,10 +4,24 @@ type CreateLinkDTO struct {
 	URL   string   `json:"url" binding:"required,url"`
 	Title string   `json:"title" binding:"required"`
 	Tags  []string `json:"tags"`
+	Notes string   `json:"notes"`
+	// Collection du lien, qui doit appartenir à l'utilisateur
+	CollectionID *uint `json:"collection_id"`
 }
 
 type UpdateLinkDTO struct {
 	URL   *string   `json:"url" binding:"omitempty,url"` // optionnel mais validé s’il est là
 	Title *string   `json:"title" binding:"omitempty"`   // idem
 	Tags  *[]string `json:"tags"`                        // facultatif
+	Notes *string   `json:"notes"`
+	Read  *bool     `json:"read"` // marque le lien comme lu ou non lu
+	// Archive le lien ou le remet dans la liste
+	Archived *bool `json:"archived"`
+	// 0 retire le lien de sa collection
+	CollectionID *uint `json:"collection_id"`
+}
+
+type CollectionDTO struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
 }
@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ArchiveVersion is the version of the archive format written by this server.
+// Archives of a newer version are refused.
+const ArchiveVersion = 1
+
+// ArchiveFile is the name of the JSON file inside a zip archive
+const ArchiveFile = "vault.json"
+
+// Archive is the export of everything a user owns. IDs are the ones of the exporting
+// instance, they only link the links to their collection.
+type Archive struct {
+	Version     int                 `json:"version"`
+	ExportedAt  time.Time           `json:"exported_at"`
+	Email       string              `json:"email"`
+	Collections []ArchiveCollection `json:"collections"`
+	Links       []ArchiveLink       `json:"links"`
+}
+
+type ArchiveCollection struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ArchiveLink struct {
+	URL          string     `json:"url"`
+	Title        string     `json:"title"`
+	Tags         []string   `json:"tags"`
+	Description  string     `json:"description,omitempty"`
+	Image        string     `json:"image,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	ReadAt       *time.Time `json:"read_at,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	CollectionID *uint      `json:"collection_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+var (
+	ErrArchiveVersion  = errors.New("unsupported archive version")
+	ErrArchiveTooLarge = errors.New("the archive is too large once uncompressed")
+)
+
+// WriteArchiveZip writes the archive as the vault.json file of a zip
+func WriteArchiveZip(w io.Writer, archive Archive) error {
+	out := zip.NewWriter(w)
+	file, err := out.CreateHeader(&zip.FileHeader{Name: ArchiveFile, Method: zip.Deflate, Modified: archive.ExportedAt})
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(archive); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// ReadArchive reads an archive, as JSON or as a zip containing vault.json. The vault.json of a
+// zip is refused beyond maxSize bytes once uncompressed.
+func ReadArchive(raw []byte, maxSize int64) (Archive, error) {
+	var archive Archive
+	if bytes.HasPrefix(raw, []byte("PK")) {
+		zipped, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			return archive, err
+		}
+		file, err := zipped.Open(ArchiveFile)
+		if err != nil {
+			return archive, fmt.Errorf("the zip has no %s file", ArchiveFile)
+		}
+		defer file.Close()
+		// La taille annoncée par le zip n'est pas fiable : on lit au plus maxSize+1 octets
+		if raw, err = io.ReadAll(io.LimitReader(file, maxSize+1)); err != nil {
+			return archive, err
+		}
+		if int64(len(raw)) > maxSize {
+			return archive, ErrArchiveTooLarge
+		}
+	}
+
+	if err := json.Unmarshal(raw, &archive); err != nil {
+		return archive, err
+	}
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return archive, ErrArchiveVersion
+	}
+	return archive, nil
+}
@@ -0,0 +1,34 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadArchiveZip(t *testing.T) {
+	var buf bytes.Buffer
+	archive := Archive{Version: ArchiveVersion, ExportedAt: time.Now(), Links: []ArchiveLink{{URL: "https://go.dev", Title: "Go"}}}
+	assert.NoError(t, WriteArchiveZip(&buf, archive))
+
+	read, err := ReadArchive(buf.Bytes(), 1<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://go.dev", read.Links[0].URL)
+
+	// Quelques Ko compressés qui en font des centaines une fois décompressés
+	var bomb bytes.Buffer
+	out := zip.NewWriter(&bomb)
+	file, err := out.CreateHeader(&zip.FileHeader{Name: ArchiveFile, Method: zip.Deflate})
+	assert.NoError(t, err)
+	_, err = file.Write([]byte(`{"version": 1, "email": "` + strings.Repeat("a", 1<<20) + `"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, out.Close())
+	assert.Less(t, bomb.Len(), 1<<16)
+
+	_, err = ReadArchive(bomb.Bytes(), 1<<16)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
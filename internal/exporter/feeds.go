@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Feed describes an Atom or RSS feed. ID is a stable URI without secret, the IDs of the
+// entries are built from it.
+type Feed struct {
+	ID          string
+	Title       string
+	HomePageURL string
+	FeedURL     string
+}
+
+func (f Feed) entryID(bookmark Bookmark) string {
+	return fmt.Sprintf("%s/%d", f.ID, bookmark.ID)
+}
+
+// updated returns the date of the last change of the bookmarks
+func updated(bookmarks []Bookmark) time.Time {
+	var last time.Time
+	for _, bookmark := range bookmarks {
+		if bookmark.ModifiedAt.After(last) {
+			last = bookmark.ModifiedAt
+		}
+	}
+	if last.IsZero() {
+		last = time.Now()
+	}
+	return last.UTC()
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    string         `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// WriteAtom writes the bookmarks as an Atom 1.0 feed, the notes are the content of the entries
+func WriteAtom(w io.Writer, feed Feed, bookmarks []Bookmark) error {
+	out := atomFeed{
+		ID:      feed.ID,
+		Title:   feed.Title,
+		Updated: updated(bookmarks).Format(time.RFC3339),
+	}
+	if feed.HomePageURL != "" {
+		out.Links = append(out.Links, atomLink{Href: feed.HomePageURL, Rel: "alternate"})
+	}
+	if feed.FeedURL != "" {
+		out.Links = append(out.Links, atomLink{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, bookmark := range bookmarks {
+		entry := atomEntry{
+			ID:        feed.entryID(bookmark),
+			Title:     bookmark.Title,
+			Link:      atomLink{Href: bookmark.URL},
+			Published: bookmark.AddedAt.UTC().Format(time.RFC3339),
+			Updated:   bookmark.ModifiedAt.UTC().Format(time.RFC3339),
+			Summary:   bookmark.Description,
+			Content:   bookmark.Notes,
+		}
+		for _, tag := range bookmark.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return writeXML(w, out)
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// WriteRSS writes the bookmarks as an RSS 2.0 feed. RSS has a single text per item: the
+// notes, or the description of the page.
+func WriteRSS(w io.Writer, feed Feed, bookmarks []Bookmark) error {
+	out := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.HomePageURL,
+			Description:   feed.Title,
+			LastBuildDate: updated(bookmarks).Format(time.RFC1123Z),
+		},
+	}
+	for _, bookmark := range bookmarks {
+		description := bookmark.Notes
+		if description == "" {
+			description = bookmark.Description
+		}
+		out.Channel.Items = append(out.Channel.Items, rssItem{
+			Title:       bookmark.Title,
+			Link:        bookmark.URL,
+			Description: description,
+			GUID:        rssGUID{Value: feed.entryID(bookmark)},
+			PubDate:     bookmark.AddedAt.UTC().Format(time.RFC1123Z),
+			Categories:  bookmark.Tags,
+		})
+	}
+	return writeXML(w, out)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
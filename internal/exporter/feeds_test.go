@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAtom(t *testing.T) {
+	var out bytes.Buffer
+	feed := Feed{ID: "https://vault.example.com/feeds/users/1", Title: "Links & more", FeedURL: "https://vault.example.com/feeds/secret/users/1"}
+	assert.NoError(t, WriteAtom(&out, feed, sample))
+
+	var parsed struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			ID    string `xml:"id"`
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Content    string `xml:"content"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+		} `xml:"entry"`
+	}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &parsed))
+	assert.Equal(t, "Links & more", parsed.Title)
+	assert.Len(t, parsed.Entries, 2)
+	assert.Equal(t, "https://vault.example.com/feeds/users/1/1", parsed.Entries[0].ID)
+	assert.Equal(t, "https://go.dev/", parsed.Entries[0].Link.Href)
+	assert.Len(t, parsed.Entries[0].Categories, 2)
+	assert.Equal(t, "To read", parsed.Entries[1].Content)
+}
+
+func TestWriteRSS(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteRSS(&out, Feed{ID: "urn:feed", Title: "Links"}, sample))
+
+	var parsed struct {
+		Version string `xml:"version,attr"`
+		Items   []struct {
+			Link        string   `xml:"link"`
+			Description string   `xml:"description"`
+			GUID        string   `xml:"guid"`
+			Categories  []string `xml:"category"`
+		} `xml:"channel>item"`
+	}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &parsed))
+	assert.Equal(t, "2.0", parsed.Version)
+	assert.Len(t, parsed.Items, 2)
+	assert.Equal(t, "The Go language", parsed.Items[0].Description)
+	assert.Equal(t, "urn:feed/2", parsed.Items[1].GUID)
+	assert.Equal(t, []string{"go", "lang"}, parsed.Items[0].Categories)
+}
@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteMarkdown writes the bookmarks as lists grouped by tag, a bookmark with several tags
+// is in several groups. Bookmarks without tag come last.
+func WriteMarkdown(w io.Writer, title string, bookmarks []Bookmark) error {
+	groups := map[string][]Bookmark{}
+	names := map[string]string{}
+	var untagged []Bookmark
+	for _, bookmark := range bookmarks {
+		if len(bookmark.Tags) == 0 {
+			untagged = append(untagged, bookmark)
+			continue
+		}
+		for _, tag := range bookmark.Tags {
+			key := strings.ToLower(tag)
+			if _, ok := names[key]; !ok {
+				names[key] = tag
+			}
+			groups[key] = append(groups[key], bookmark)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# %s\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(out, "\n## %s\n\n", markdownText(names[key]))
+		writeMarkdownList(out, groups[key])
+	}
+	if len(untagged) > 0 {
+		out.WriteString("\n## Untagged\n\n")
+		writeMarkdownList(out, untagged)
+	}
+	return out.Flush()
+}
+
+func writeMarkdownList(out *bufio.Writer, bookmarks []Bookmark) {
+	for _, bookmark := range bookmarks {
+		// Les parenthèses de l'URL casseraient le lien
+		url := strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(bookmark.URL)
+		fmt.Fprintf(out, "- [%s](%s)", markdownText(bookmark.Title), url)
+		if bookmark.Description != "" {
+			fmt.Fprintf(out, " — %s", markdownText(bookmark.Description))
+		}
+		out.WriteString("\n")
+	}
+}
+
+// markdownText escapes the characters with a meaning in Markdown and keeps the text on one line
+func markdownText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer(
+		`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, "#", `\#`,
+	).Replace(text)
+}
+
+// WriteCSV writes one line per bookmark, for spreadsheets
+func WriteCSV(w io.Writer, bookmarks []Bookmark) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"url", "title", "tags", "description", "notes", "created_at", "read_at"})
+	for _, bookmark := range bookmarks {
+		readAt := ""
+		if bookmark.ReadAt != nil {
+			readAt = bookmark.ReadAt.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{
+			spreadsheetSafe(bookmark.URL),
+			spreadsheetSafe(bookmark.Title),
+			spreadsheetSafe(strings.Join(bookmark.Tags, ", ")),
+			spreadsheetSafe(bookmark.Description),
+			spreadsheetSafe(bookmark.Notes),
+			bookmark.AddedAt.UTC().Format(time.RFC3339),
+			readAt,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// spreadsheetSafe stops spreadsheets from running a value as a formula
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// JSONFeed is a feed in the JSON Feed 1.1 format (https://jsonfeed.org/version/1.1)
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentText   string    `json:"content_text"`
+	Summary       string    `json:"summary,omitempty"`
+	DatePublished time.Time `json:"date_published"`
+	DateModified  time.Time `json:"date_modified"`
+	Tags          []string  `json:"tags,omitempty"`
+}
+
+// WriteJSONFeed writes the bookmarks as a JSON Feed. The notes are the content of the items,
+// the description of the page their summary.
+func WriteJSONFeed(w io.Writer, feed JSONFeed, bookmarks []Bookmark) error {
+	feed.Version = "https://jsonfeed.org/version/1.1"
+	feed.Items = make([]JSONFeedItem, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		content := bookmark.Notes
+		if content == "" {
+			content = bookmark.Description
+		}
+		feed.Items = append(feed.Items, JSONFeedItem{
+			ID:            fmt.Sprint(bookmark.ID),
+			URL:           bookmark.URL,
+			Title:         bookmark.Title,
+			ContentText:   content,
+			Summary:       bookmark.Description,
+			DatePublished: bookmark.AddedAt.UTC(),
+			DateModified:  bookmark.ModifiedAt.UTC(),
+			Tags:          bookmark.Tags,
+		})
+	}
+	return json.NewEncoder(w).Encode(feed)
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sample = []Bookmark{
+	{ID: 1, URL: "https://go.dev/", Title: "Go *fast*", Description: "The Go language", Tags: []string{"go", "lang"}, AddedAt: time.Unix(1600000000, 0)},
+	{ID: 2, URL: "https://en.wikipedia.org/wiki/Zig_(language)", Title: "=HYPERLINK(\"x\")", Notes: "To read", AddedAt: time.Unix(1600000100, 0)},
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteMarkdown(&out, "Links", sample))
+	assert.Equal(t, "# Links\n\n"+
+		"## go\n\n- [Go \\*fast\\*](https://go.dev/) — The Go language\n\n"+
+		"## lang\n\n- [Go \\*fast\\*](https://go.dev/) — The Go language\n\n"+
+		"## Untagged\n\n- [=HYPERLINK(\"x\")](https://en.wikipedia.org/wiki/Zig_%28language%29)\n", out.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteCSV(&out, sample))
+	rows, err := csv.NewReader(&out).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, []string{"https://go.dev/", "Go *fast*", "go, lang", "The Go language", "", "2020-09-13T12:26:40Z", ""}, rows[1])
+	// Une formule n'est pas exécutée par le tableur
+	assert.Equal(t, `'=HYPERLINK("x")`, rows[2][1])
+}
+
+func TestWriteJSONFeed(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteJSONFeed(&out, JSONFeed{Title: "Links"}, sample))
+	var feed JSONFeed
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &feed))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", feed.Version)
+	assert.Len(t, feed.Items, 2)
+	assert.Equal(t, "1", feed.Items[0].ID)
+	assert.Equal(t, "The Go language", feed.Items[0].ContentText)
+	assert.Equal(t, "To read", feed.Items[1].ContentText)
+}
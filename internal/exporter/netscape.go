@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// Bookmark is a link written to an export
+type Bookmark struct {
+	ID          uint
+	URL         string
+	Title       string
+	Description string
+	Notes       string
+	Tags        []string
+	AddedAt     time.Time
+	ModifiedAt  time.Time
+	ReadAt      *time.Time
+}
+
+// Folder groups bookmarks under a name
+type Folder struct {
+	Name       string
+	AddedAt    time.Time
+	ModifiedAt time.Time
+	Bookmarks  []Bookmark
+}
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// WriteNetscape writes the bookmarks.html format read by every browser: the folders first,
+// then the bookmarks outside of any folder.
+func WriteNetscape(w io.Writer, folders []Folder, bookmarks []Bookmark) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(netscapeHeader)
+	out.WriteString("<DL><p>\n")
+	for _, folder := range folders {
+		fmt.Fprintf(out, "    <DT><H3%s>%s</H3>\n", dates(folder.AddedAt, folder.ModifiedAt), html.EscapeString(folder.Name))
+		out.WriteString("    <DL><p>\n")
+		for _, bookmark := range folder.Bookmarks {
+			writeBookmark(out, "        ", bookmark)
+		}
+		out.WriteString("    </DL><p>\n")
+	}
+	for _, bookmark := range bookmarks {
+		writeBookmark(out, "    ", bookmark)
+	}
+	out.WriteString("</DL><p>\n")
+	return out.Flush()
+}
+
+func writeBookmark(out *bufio.Writer, indent string, bookmark Bookmark) {
+	fmt.Fprintf(out, `%s<DT><A HREF="%s"%s`, indent, html.EscapeString(bookmark.URL), dates(bookmark.AddedAt, bookmark.ModifiedAt))
+	if len(bookmark.Tags) > 0 {
+		fmt.Fprintf(out, ` TAGS="%s"`, html.EscapeString(strings.Join(bookmark.Tags, ",")))
+	}
+	fmt.Fprintf(out, ">%s</A>\n", html.EscapeString(bookmark.Title))
+	if bookmark.Description != "" {
+		fmt.Fprintf(out, "%s<DD>%s\n", indent, html.EscapeString(bookmark.Description))
+	}
+}
+
+// dates renders the ADD_DATE and LAST_MODIFIED attributes, in Unix seconds
+func dates(added, modified time.Time) string {
+	var attrs string
+	if !added.IsZero() {
+		attrs += fmt.Sprintf(` ADD_DATE="%d"`, added.Unix())
+	}
+	if !modified.IsZero() {
+		attrs += fmt.Sprintf(` LAST_MODIFIED="%d"`, modified.Unix())
+	}
+	return attrs
+}
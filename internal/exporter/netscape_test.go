@@ -0,0 +1,29 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/importer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteNetscapeRoundTrip(t *testing.T) {
+	added := time.Unix(1600000000, 0)
+	var out bytes.Buffer
+	err := WriteNetscape(&out, []Folder{{
+		Name:      "Dev & tools",
+		Bookmarks: []Bookmark{{URL: "https://go.dev/?a=1&b=2", Title: `The "Go" language`, Tags: []string{"go", "lang"}, AddedAt: added, Description: "Simple <and> fast"}},
+	}}, []Bookmark{{URL: "https://example.com/", Title: "Example"}})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `ADD_DATE="1600000000"`)
+
+	// Le fichier produit se relit comme un export de navigateur
+	bookmarks, err := importer.ParseNetscape(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, []importer.Bookmark{
+		{URL: "https://go.dev/?a=1&b=2", Title: `The "Go" language`, Description: "Simple <and> fast", Tags: []string{"go", "lang"}, Folders: []string{"Dev & tools"}, AddedAt: added},
+		{URL: "https://example.com/", Title: "Example"},
+	}, bookmarks)
+}
@@ -0,0 +1,318 @@
+package feedreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxFeedSize caps the size of a downloaded feed
+const maxFeedSize = 5 << 20
+
+var ErrUnknownFormat = errors.New("not an RSS, Atom or JSON Feed document")
+
+// Item is an entry of a feed
+type Item struct {
+	ID        string // guid, id... ou l'URL à défaut
+	URL       string
+	Title     string
+	Summary   string
+	Published time.Time
+	Tags      []string
+}
+
+// Result is the answer to a poll. NotModified is set when the server answered 304 to the
+// validators of the previous poll, Items is then empty.
+type Result struct {
+	NotModified  bool
+	ETag         string
+	LastModified string
+	Title        string
+	Items        []Item
+}
+
+// Fetch downloads and parses the feed. etag and lastModified are the validators of the
+// previous poll, sent so the server can answer 304 when nothing changed.
+func Fetch(client *http.Client, feedURL, etag, lastModified string) (Result, error) {
+	req, err := http.NewRequest(http.MethodGet, feedURL, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/atom+xml, application/rss+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	req.Header.Set("User-Agent", "go_link_vault feed reader")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	result := Result{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if resp.StatusCode == http.StatusNotModified {
+		// Le serveur peut ne pas renvoyer les validateurs avec un 304
+		if result.ETag == "" {
+			result.ETag = etag
+		}
+		if result.LastModified == "" {
+			result.LastModified = lastModified
+		}
+		result.NotModified = true
+		return result, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return Result{}, err
+	}
+	if len(data) > maxFeedSize {
+		return Result{}, fmt.Errorf("feed larger than %d MB", maxFeedSize>>20)
+	}
+
+	// Les liens relatifs se résolvent par rapport à l'URL finale, après redirections
+	result.Title, result.Items, err = Parse(data, resp.Request.URL.String())
+	return result, err
+}
+
+// Parse reads an RSS (0.9x, 1.0 or 2.0), Atom or JSON Feed document and returns its title and
+// entries. Relative URLs are resolved against base.
+func Parse(data []byte, base string) (string, []Item, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	var title string
+	var items []Item
+	var err error
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		title, items, err = parseJSONFeed(trimmed)
+	} else {
+		title, items, err = parseXML(trimmed)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	baseURL, _ := url.Parse(base)
+	for i := range items {
+		items[i].Title = strings.TrimSpace(items[i].Title)
+		items[i].URL = resolve(baseURL, strings.TrimSpace(items[i].URL))
+		if items[i].ID == "" {
+			items[i].ID = items[i].URL
+		}
+	}
+	return strings.TrimSpace(title), items, nil
+}
+
+func resolve(base *url.URL, link string) string {
+	if base == nil || link == "" {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+type jsonFeed struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Items   []struct {
+		ID            interface{} `json:"id"` // chaîne en 1.1, parfois un nombre
+		URL           string      `json:"url"`
+		ExternalURL   string      `json:"external_url"`
+		Title         string      `json:"title"`
+		Summary       string      `json:"summary"`
+		ContentText   string      `json:"content_text"`
+		DatePublished string      `json:"date_published"`
+		Tags          []string    `json:"tags"`
+	} `json:"items"`
+}
+
+func parseJSONFeed(data []byte) (string, []Item, error) {
+	var feed jsonFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return "", nil, ErrUnknownFormat
+	}
+
+	items := make([]Item, 0, len(feed.Items))
+	for _, entry := range feed.Items {
+		item := Item{
+			URL:       entry.URL,
+			Title:     entry.Title,
+			Summary:   entry.Summary,
+			Published: parseTime(entry.DatePublished),
+			Tags:      entry.Tags,
+		}
+		if entry.ID != nil {
+			item.ID = fmt.Sprint(entry.ID)
+		}
+		if item.URL == "" {
+			item.URL = entry.ExternalURL
+		}
+		if item.Summary == "" {
+			item.Summary = entry.ContentText
+		}
+		items = append(items, item)
+	}
+	return feed.Title, items, nil
+}
+
+// xmlLink is the link of an Atom entry (href attribute) or of an RSS item (text)
+type xmlLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+type xmlCategory struct {
+	Term string `xml:"term,attr"`
+	Text string `xml:",chardata"`
+}
+
+// xmlEntry reads both the RSS items and the Atom entries, by local name
+type xmlEntry struct {
+	ID          string        `xml:"id"`
+	GUID        string        `xml:"guid"`
+	Title       string        `xml:"title"`
+	Links       []xmlLink     `xml:"link"`
+	Description string        `xml:"description"`
+	Summary     string        `xml:"summary"`
+	PubDate     string        `xml:"pubDate"`
+	Published   string        `xml:"published"`
+	Updated     string        `xml:"updated"`
+	Date        string        `xml:"date"` // dc:date en RSS 1.0
+	Categories  []xmlCategory `xml:"category"`
+	Subjects    []string      `xml:"subject"` // dc:subject
+}
+
+type xmlFeed struct {
+	XMLName xml.Name
+	Title   string     `xml:"title"`
+	Entries []xmlEntry `xml:"entry"` // Atom
+	Items   []xmlEntry `xml:"item"`  // RSS 1.0
+	Channel struct {
+		Title string     `xml:"title"`
+		Items []xmlEntry `xml:"item"`
+	} `xml:"channel"` // RSS 0.9x et 2.0
+}
+
+func parseXML(data []byte) (string, []Item, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Beaucoup de flux contiennent des entités HTML (&nbsp;...) ou un encodage ISO-8859-1
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var feed xmlFeed
+	if err := decoder.Decode(&feed); err != nil {
+		return "", nil, err
+	}
+
+	var title string
+	var entries []xmlEntry
+	switch strings.ToLower(feed.XMLName.Local) {
+	case "feed":
+		title, entries = feed.Title, feed.Entries
+	case "rss":
+		title, entries = feed.Channel.Title, feed.Channel.Items
+	case "rdf":
+		title, entries = feed.Channel.Title, feed.Items
+	default:
+		return "", nil, ErrUnknownFormat
+	}
+
+	items := make([]Item, 0, len(entries))
+	for _, entry := range entries {
+		item := Item{
+			ID:      firstNonEmpty(entry.ID, entry.GUID),
+			URL:     entryLink(entry.Links),
+			Title:   entry.Title,
+			Summary: strings.TrimSpace(firstNonEmpty(entry.Summary, entry.Description)),
+		}
+		item.Published = parseTime(firstNonEmpty(entry.Published, entry.PubDate, entry.Date, entry.Updated))
+		for _, category := range entry.Categories {
+			if tag := strings.TrimSpace(firstNonEmpty(category.Term, category.Text)); tag != "" {
+				item.Tags = append(item.Tags, tag)
+			}
+		}
+		for _, subject := range entry.Subjects {
+			if tag := strings.TrimSpace(subject); tag != "" {
+				item.Tags = append(item.Tags, tag)
+			}
+		}
+		// Un guid d'RSS est souvent le permalien de l'article
+		if item.URL == "" && strings.HasPrefix(entry.GUID, "http") {
+			item.URL = entry.GUID
+		}
+		items = append(items, item)
+	}
+	return title, items, nil
+}
+
+// entryLink returns the alternate link of an Atom entry, or the link of an RSS item
+func entryLink(links []xmlLink) string {
+	for _, link := range links {
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return link.Href
+		}
+	}
+	for _, link := range links {
+		if text := strings.TrimSpace(link.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Formats de date rencontrés dans les flux, RFC 822 pour RSS et RFC 3339 pour Atom et JSON Feed
+var timeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime returns the zero time when the date can't be read
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
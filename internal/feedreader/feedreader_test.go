@@ -0,0 +1,108 @@
+package feedreader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const atomFeedDoc = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Go blog</title>
+  <entry>
+    <id>tag:go.dev,2024:1</id>
+    <title>Go&nbsp;1.22</title>
+    <link rel="replies" href="/comments"/>
+    <link href="/blog/go1.22"/>
+    <published>2024-02-06T00:00:00Z</published>
+    <summary>Release notes</summary>
+    <category term="release"/>
+  </entry>
+</feed>`
+
+const rssFeedDoc = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0">
+  <channel>
+    <title>Security news</title>
+    <item>
+      <title>Caf` + "\xe9" + `</title>
+      <link>https://example.com/cafe</link>
+      <guid isPermaLink="false">42</guid>
+      <pubDate>Tue, 6 Feb 2024 10:00:00 +0000</pubDate>
+      <category>security</category>
+    </item>
+    <item>
+      <title>Only a guid</title>
+      <guid>https://example.com/guid</guid>
+    </item>
+  </channel>
+</rss>`
+
+const jsonFeedDoc = `{"version": "https://jsonfeed.org/version/1.1", "title": "Links", "items": [
+  {"id": 7, "external_url": "https://example.com/external", "title": "External", "content_text": "Text", "tags": ["go"]}
+]}`
+
+func TestParseAtom(t *testing.T) {
+	title, items, err := Parse([]byte(atomFeedDoc), "https://go.dev/feed.atom")
+	assert.NoError(t, err)
+	assert.Equal(t, "Go blog", title)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "tag:go.dev,2024:1", items[0].ID)
+	assert.Equal(t, "https://go.dev/blog/go1.22", items[0].URL)
+	assert.Equal(t, "Go 1.22", items[0].Title)
+	assert.Equal(t, "Release notes", items[0].Summary)
+	assert.Equal(t, []string{"release"}, items[0].Tags)
+	assert.Equal(t, time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), items[0].Published)
+}
+
+func TestParseRSS(t *testing.T) {
+	title, items, err := Parse([]byte(rssFeedDoc), "https://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, "Security news", title)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Café", items[0].Title)
+	assert.Equal(t, "42", items[0].ID)
+	assert.Equal(t, []string{"security"}, items[0].Tags)
+	assert.False(t, items[0].Published.IsZero())
+	assert.Equal(t, "https://example.com/guid", items[1].URL)
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	title, items, err := Parse([]byte(jsonFeedDoc), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Links", title)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "7", items[0].ID)
+	assert.Equal(t, "https://example.com/external", items[0].URL)
+	assert.Equal(t, "Text", items[0].Summary)
+
+	_, _, err = Parse([]byte(`<html><body>Not a feed</body></html>`), "")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestFetchConditional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(atomFeedDoc))
+	}))
+	defer server.Close()
+
+	result, err := Fetch(server.Client(), server.URL+"/feed", "", "")
+	assert.NoError(t, err)
+	assert.False(t, result.NotModified)
+	assert.Equal(t, `"v1"`, result.ETag)
+	assert.Equal(t, server.URL+"/blog/go1.22", result.Items[0].URL)
+
+	result, err = Fetch(server.Client(), server.URL+"/feed", `"v1"`, "")
+	assert.NoError(t, err)
+	assert.True(t, result.NotModified)
+	assert.Equal(t, `"v1"`, result.ETag)
+	assert.Empty(t, result.Items)
+}
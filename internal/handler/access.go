@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Access levels of a user on a link
+const (
+	accessNone = iota
+	accessView
+	accessEdit
+)
+
+// roleAccess converts a workspace role to an access level
+func roleAccess(role string) int {
+	switch role {
+	case models.WorkspaceOwner, models.WorkspaceEditor:
+		return accessEdit
+	case models.WorkspaceViewer:
+		return accessView
+	}
+	return accessNone
+}
+
+// workspaceRole returns the role of the user in the workspace, "" if not a member
+func workspaceRole(userID, workspaceID uint) string {
+	var member models.WorkspaceMember
+	if err := db.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// permissionAccess converts the permission of a grant to an access level
+func permissionAccess(permission string) int {
+	switch permission {
+	case models.PermissionEdit:
+		return accessEdit
+	case models.PermissionView:
+		return accessView
+	}
+	return accessNone
+}
+
+// bestGrant returns the highest access given by the grants matching the query
+func bestGrant(query *gorm.DB) int {
+	var grants []models.Grant
+	query.Find(&grants)
+
+	access := accessNone
+	for _, grant := range grants {
+		access = max(access, permissionAccess(grant.Permission))
+	}
+	return access
+}
+
+// linkAccess returns what the user can do with the link: personal links are visible to their
+// owner and to the users they were granted to (directly or through their collection),
+// workspace links depend on the role of the member
+func linkAccess(user models.User, link models.Link) int {
+	if link.WorkspaceID != nil {
+		return roleAccess(workspaceRole(user.ID, *link.WorkspaceID))
+	}
+	if link.UserID == user.ID {
+		return accessEdit
+	}
+
+	grants := db.DB.Where("user_id = ? AND link_id = ?", user.ID, link.ID)
+	if link.CollectionID != nil {
+		grants = db.DB.Where("user_id = ? AND (link_id = ? OR collection_id = ?)", user.ID, link.ID, *link.CollectionID)
+	}
+	return bestGrant(grants)
+}
+
+// collectionAccess returns what the user can do with the links of the collection
+func collectionAccess(user models.User, collection models.Collection) int {
+	if collection.UserID == user.ID {
+		return accessEdit
+	}
+	return bestGrant(db.DB.Where("user_id = ? AND collection_id = ?", user.ID, collection.ID))
+}
+
+// linkForRequest loads the link of the URL and checks the user has the needed access.
+// Links the user can't see answer 404, read-only links answer 403 to modifications.
+func linkForRequest(c *gin.Context, user models.User, need int) (models.Link, bool) {
+	var link models.Link
+	if err := db.DB.Where("id = ?", c.Param("id")).First(&link).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Link not found")
+		return link, false
+	}
+
+	access := linkAccess(user, link)
+	if access == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Link not found")
+		return link, false
+	}
+	if access < need {
+		ErrorResponse(c, http.StatusForbidden, "Read-only access")
+		return link, false
+	}
+	return link, true
+}
+
+// linkScope resolves the workspace_id query parameter. Without it the request applies to the
+// personal links of the user, otherwise the user must have the needed role in the workspace.
+// The returned query selects the links of the scope.
+func linkScope(c *gin.Context, user models.User, need int) (*gorm.DB, *uint, bool) {
+	param := c.Query("workspace_id")
+	if param == "" {
+		return db.DB.Where("user_id = ? AND workspace_id IS NULL", user.ID), nil, true
+	}
+
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid workspace_id")
+		return nil, nil, false
+	}
+	workspaceID := uint(id)
+
+	access := roleAccess(workspaceRole(user.ID, workspaceID))
+	if access == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Workspace not found")
+		return nil, nil, false
+	}
+	if access < need {
+		ErrorResponse(c, http.StatusForbidden, "Read-only access")
+		return nil, nil, false
+	}
+	return db.DB.Where("workspace_id = ?", workspaceID), &workspaceID, true
+}
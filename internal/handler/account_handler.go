@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangeEmailInput struct {
+	Password string `json:"password" binding:"required"`
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
+type DeleteAccountInput struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // requis si la 2FA est activée
+}
+
+// deleteAccount removes the user and everything they own, inside tx
+func deleteAccount(tx *gorm.DB, user models.User) error {
+	// Avant les liens : ceux des workspaces restent dans les workspaces
+	if err := leaveWorkspaces(tx, user); err != nil {
+		return err
+	}
+	// Liens restés à son nom dans des workspaces qu'il avait quittés
+	var workspaceIDs []uint
+	if err := tx.Unscoped().Model(&models.Link{}).Where("user_id = ? AND workspace_id IS NOT NULL", user.ID).
+		Distinct().Pluck("workspace_id", &workspaceIDs).Error; err != nil {
+		return err
+	}
+	for _, workspaceID := range workspaceIDs {
+		if err := handOverLinks(tx, workspaceID, user.ID); err != nil {
+			return err
+		}
+	}
+	var linkIDs []uint
+	if err := tx.Unscoped().Model(&models.Link{}).Where("user_id = ? AND workspace_id IS NULL", user.ID).Pluck("id", &linkIDs).Error; err != nil {
+		return err
+	}
+	if err := purgeLinks(tx, linkIDs); err != nil {
+		return err
+	}
+	// Ses commentaires sur les liens des autres
+	comments := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("user_id = ?", user.ID)
+	if err := tx.Where("comment_id IN (?)", comments).Delete(&models.CommentMention{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.Comment{},
+		&models.CommentMention{},
+		&models.EmailVerification{},
+		&models.RecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.ExternalIdentity{},
+		&models.Share{},
+		&models.Grant{},
+		&models.Collection{},
+		&models.ImportJob{},
+		&models.FeedToken{},
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := deleteSubscriptions(tx, "user_id = ?", user.ID); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("owner_id = ?", user.ID).Delete(&models.Grant{}).Error; err != nil {
+		return err
+	}
+	// Les versions qu'il a faites des liens des autres restent, sans auteur
+	if err := tx.Model(&models.LinkRevision{}).Where("editor_id = ?", user.ID).Update("editor_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("key = ?", throttle.AccountKey(user.Email)).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&user).Error
+}
+
+// GetAccountHandler returns the profile of the logged-in user
+func GetAccountHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"id":                 user.ID,
+		"email":              user.Email,
+		"email_verified":     user.IsVerified(),
+		"two_factor_enabled": user.TwoFactorEnabled,
+		"created_at":         user.CreatedAt,
+	})
+}
+
+// ChangePasswordHandler updates the password and revokes every other session.
+// A new token is returned for the current client.
+func ChangePasswordHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !auth.CheckPasswordHash(input.CurrentPassword, user.Password) {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(input.NewPassword)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Error hashing password")
+		return
+	}
+
+	// Les tokens précédents deviennent invalides
+	user.SessionVersion++
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"password":        hashedPassword,
+		"session_version": user.SessionVersion,
+	}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update password")
+		return
+	}
+
+	token, err := auth.CreateToken(user)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Error generating JWT")
+		return
+	}
+
+	audit.Record(models.AuditEvent{ActorID: &user.ID, Action: "account.password_change", IP: c.ClientIP()}, nil)
+
+	// En session cookie, le nouveau token remplace le cookie et n'est pas exposé au JS
+	if middleware.CookieSession(c) {
+		csrf, err := middleware.SetSessionCookies(c, token)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Error generating CSRF token")
+			return
+		}
+		SuccessResponse(c, http.StatusOK, gin.H{"csrf_token": csrf})
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"token": token})
+}
+
+// ChangeEmailHandler sends a confirmation link to the new address.
+// The email is only replaced once the link is opened.
+func ChangeEmailHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input ChangeEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !auth.CheckPasswordHash(input.Password, user.Password) {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	newEmail := strings.TrimSpace(input.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		ErrorResponse(c, http.StatusBadRequest, "New email is the current one")
+		return
+	}
+
+	if emailTaken(newEmail, user.ID) {
+		ErrorResponse(c, http.StatusConflict, "Email already in use")
+		return
+	}
+
+	if err := sendVerificationEmail(user, newEmail); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not send verification email")
+		return
+	}
+
+	// Prévenir l'ancienne adresse en cas de compromission du compte
+	if err := mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Go Link Vault email address is being changed",
+		Body:    "A change of your account email to " + newEmail + " was requested. If it wasn't you, change your password now.",
+	}); err != nil {
+		logger.ErrorLogger.Println("Failed to notify email change:", err)
+	}
+
+	audit.Record(models.AuditEvent{ActorID: &user.ID, Action: "account.email_change_requested", IP: c.ClientIP()}, gin.H{"new_email": newEmail})
+
+	SuccessResponse(c, http.StatusAccepted, gin.H{"message": "Verification email sent to the new address"})
+}
+
+// DeleteAccountHandler permanently deletes the account with all its links and related data
+func DeleteAccountHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input DeleteAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !auth.CheckPasswordHash(input.Password, user.Password) {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+	if user.TwoFactorEnabled && !checkTOTP(&user, input.Code) {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteAccount(tx, user)
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete account")
+		return
+	}
+
+	audit.Record(models.AuditEvent{ActorID: &user.ID, Action: "account.delete", IP: c.ClientIP()}, nil)
+	middleware.ClearSessionCookies(c)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Account deleted"})
+}
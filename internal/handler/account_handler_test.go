@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func accountRouter() *gin.Engine {
+	r := gin.Default()
+	r.GET("/account", middleware.AuthRequired(), GetAccountHandler)
+	r.PUT("/account/password", middleware.AuthRequired(), ChangePasswordHandler)
+	r.PUT("/account/email", middleware.AuthRequired(), ChangeEmailHandler)
+	r.DELETE("/account", middleware.AuthRequired(), DeleteAccountHandler)
+	r.GET("/verify-email", VerifyEmailHandler)
+	return r
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	db.SetupTestDB()
+
+	hashedPwd, _ := auth.HashPassword("oldpassword")
+	user := models.User{Email: "password@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	oldToken, _ := auth.CreateToken(user)
+
+	router := accountRouter()
+
+	resp := sendJSON(router, "PUT", "/account/password", oldToken, map[string]string{
+		"current_password": "wrongpassword",
+		"new_password":     "newpassword",
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = sendJSON(router, "PUT", "/account/password", oldToken, map[string]string{
+		"current_password": "oldpassword",
+		"new_password":     "newpassword",
+	})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body ResponseData[map[string]string]
+	assert.NoError(t, jsonDecode(resp, &body))
+	newToken := body.Data["token"]
+
+	// L'ancien token est révoqué, le nouveau fonctionne
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(router, "GET", "/account", oldToken, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", "/account", newToken, nil).Code)
+
+	assert.NoError(t, db.DB.First(&user, user.ID).Error)
+	assert.True(t, auth.CheckPasswordHash("newpassword", user.Password))
+}
+
+func TestChangeEmailRequiresVerification(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: "old@example.com", Password: hashedPwd}
+	other := models.User{Email: "taken@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	assert.NoError(t, db.DB.Create(&other).Error)
+	token, _ := auth.CreateToken(user)
+
+	router := accountRouter()
+
+	resp := sendJSON(router, "PUT", "/account/email", token, map[string]string{"password": "password", "new_email": "taken@example.com"})
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = sendJSON(router, "PUT", "/account/email", token, map[string]string{"password": "password", "new_email": "new@example.com"})
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+
+	// L'email ne change qu'après confirmation
+	assert.NoError(t, db.DB.First(&user, user.ID).Error)
+	assert.Equal(t, "old@example.com", user.Email)
+
+	assert.Len(t, rec.sent, 2)
+	assert.Equal(t, "new@example.com", rec.sent[0].To)
+	assert.Equal(t, "old@example.com", rec.sent[1].To)
+
+	resp = sendJSON(router, "GET", "/verify-email?token="+url.QueryEscape(tokenFromMail(t, rec.sent[0])), "", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.NoError(t, db.DB.First(&user, user.ID).Error)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.True(t, user.IsVerified())
+
+	// Le token existant reste valide : il identifie l'utilisateur par son ID
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", "/account", token, nil).Code)
+}
+
+func TestDeleteAccountCascades(t *testing.T) {
+	db.SetupTestDB()
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: "delete-account@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	link := models.Link{URL: "https://example.com", Title: "Example", Tags: toJSON([]string{"a"}), UserID: user.ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+	token, _ := auth.CreateToken(user)
+
+	router := accountRouter()
+
+	resp := sendJSON(router, "DELETE", "/account", token, map[string]string{"password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = sendJSON(router, "DELETE", "/account", token, map[string]string{"password": "password"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var count int64
+	db.DB.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	assert.Zero(t, count)
+	db.DB.Unscoped().Model(&models.Link{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Zero(t, count)
+
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(router, "GET", "/account", token, nil).Code)
+}
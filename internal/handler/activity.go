@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ActivityView is an event of the activity feed
+type ActivityView struct {
+	ID          uint           `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	ActorID     *uint          `json:"actor_id,omitempty"`
+	ActorEmail  string         `json:"actor_email,omitempty"`
+	Action      string         `json:"action"`
+	TargetType  string         `json:"target_type"`
+	TargetID    string         `json:"target_id"`
+	WorkspaceID *uint          `json:"workspace_id,omitempty"`
+	Before      datatypes.JSON `json:"before,omitempty"`
+	After       datatypes.JSON `json:"after,omitempty"`
+}
+
+// linkSnapshot holds the fields of a link tracked by the activity log
+func linkSnapshot(link models.Link) gin.H {
+	return gin.H{
+		"url":           link.URL,
+		"title":         link.Title,
+		"tags":          link.Tags,
+		"description":   link.Description,
+		"image":         link.Image,
+		"notes":         link.Notes,
+		"read_at":       link.ReadAt,
+		"archived_at":   link.ArchivedAt,
+		"collection_id": link.CollectionID,
+		"workspace_id":  link.WorkspaceID,
+	}
+}
+
+// recordLinkChange records the creation (before nil), update or deletion (after nil) of a link.
+// Tag changes appear in the "tags" field of the diff.
+func recordLinkChange(c *gin.Context, actor models.User, action string, before, after *models.Link) {
+	link := after
+	if link == nil {
+		link = before
+	}
+
+	event := models.AuditEvent{
+		ActorID:     &actor.ID,
+		Action:      action,
+		TargetType:  "link",
+		TargetID:    strconv.FormatUint(uint64(link.ID), 10),
+		IP:          c.ClientIP(),
+		WorkspaceID: link.WorkspaceID,
+	}
+	if link.WorkspaceID == nil {
+		ownerID := link.UserID
+		event.OwnerID = &ownerID
+	}
+
+	var oldValues, newValues interface{}
+	if before != nil {
+		oldValues = linkSnapshot(*before)
+	}
+	if after != nil {
+		newValues = linkSnapshot(*after)
+	}
+	audit.Change(event, oldValues, newValues)
+}
+
+// recordCollectionChange records the creation, update or deletion of a collection
+func recordCollectionChange(c *gin.Context, actor models.User, action string, before, after *models.Collection) {
+	collection := after
+	if collection == nil {
+		collection = before
+	}
+
+	ownerID := collection.UserID
+	event := models.AuditEvent{
+		ActorID:    &actor.ID,
+		Action:     action,
+		TargetType: "collection",
+		TargetID:   strconv.FormatUint(uint64(collection.ID), 10),
+		IP:         c.ClientIP(),
+		OwnerID:    &ownerID,
+	}
+
+	var oldValues, newValues interface{}
+	if before != nil {
+		oldValues = gin.H{"name": before.Name, "description": before.Description}
+	}
+	if after != nil {
+		newValues = gin.H{"name": after.Name, "description": after.Description}
+	}
+	audit.Change(event, oldValues, newValues)
+}
+
+var errInvalidTime = errors.New("from and to must be RFC 3339 dates")
+
+// filterEvents applies the action (exact, or prefix ending with *), from and to query parameters
+func filterEvents(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if action := c.Query("action"); action != "" {
+		if prefix, ok := strings.CutSuffix(action, "*"); ok {
+			query = query.Where("audit_events.action LIKE ?", prefix+"%")
+		} else {
+			query = query.Where("audit_events.action = ?", action)
+		}
+	}
+	for param, condition := range map[string]string{
+		"from": "audit_events.created_at >= ?",
+		"to":   "audit_events.created_at <= ?",
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errInvalidTime
+		}
+		query = query.Where(condition, at)
+	}
+	return query, nil
+}
+
+// listEvents answers a page of the events selected by query, most recent first
+func listEvents(c *gin.Context, query *gorm.DB) {
+	query, err := filterEvents(c, query)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, perPage := pagination(c)
+
+	var total int64
+	query.Count(&total)
+
+	events := []ActivityView{}
+	if err := query.
+		Select("audit_events.id, audit_events.created_at, audit_events.actor_id, users.email AS actor_email, audit_events.action, " +
+			"audit_events.target_type, audit_events.target_id, audit_events.workspace_id, audit_events.old_values AS before, audit_events.new_values AS after").
+		Joins("LEFT JOIN users ON users.id = audit_events.actor_id").
+		Order("audit_events.id DESC").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Scan(&events).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch events")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"events":   events,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// ActivityHandler is the activity feed of the user: changes of their links and collections,
+// whoever made them, and changes in their workspaces. Filterable by action, from and to.
+func ActivityHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspaces := db.DB.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", user.ID)
+	query := db.DB.Model(&models.AuditEvent{}).
+		Where("audit_events.target_type IN ?", []string{"link", "collection"}).
+		Where("audit_events.owner_id = ? OR audit_events.workspace_id IN (?) OR audit_events.actor_id = ?", user.ID, workspaces, user.ID)
+	if workspaceID := c.Query("workspace_id"); workspaceID != "" {
+		query = query.Where("audit_events.workspace_id = ?", workspaceID)
+	}
+
+	listEvents(c, query)
+}
+
+// AuditLogHandler lets admins query every event, filterable by actor_id, action, target_type, from and to
+func AuditLogHandler(c *gin.Context) {
+	query := db.DB.Model(&models.AuditEvent{})
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("audit_events.actor_id = ?", actorID)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("audit_events.target_type = ?", targetType)
+	}
+
+	listEvents(c, query)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type activityPage struct {
+	Events []ActivityView `json:"events"`
+	Total  int64          `json:"total"`
+}
+
+func activityRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/activity", middleware.AuthRequired(), ActivityHandler)
+	admin := r.Group("/admin", middleware.AuthRequired(), middleware.AdminRequired())
+	admin.GET("/audit", AuditLogHandler)
+	return r
+}
+
+func TestActivityFeed(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "member@example.com", "outsider@example.com")
+	owner, member, outsider := tokens[0], tokens[1], tokens[2]
+	router := activityRouter()
+
+	resp := postJSON(router, "/workspaces", owner, map[string]string{"name": "Team"})
+	var workspace ResponseData[WorkspaceView]
+	assert.NoError(t, jsonDecode(resp, &workspace))
+	wsID := workspace.Data.ID
+	joinWorkspace(t, router, rec, wsID, owner, "member@example.com", member, "editor")
+
+	resp = postJSON(router, fmt.Sprintf("/links?workspace_id=%d", wsID), owner, map[string]interface{}{"url": "https://go.dev", "title": "Go", "tags": []string{"go"}})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var link models.Link
+	assert.NoError(t, db.DB.Where("workspace_id = ?", wsID).First(&link).Error)
+	path := fmt.Sprintf("/link/%d", link.ID)
+
+	// Une mise à jour sans changement n'est pas enregistrée
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, member, map[string]interface{}{"tags": []string{"go", "lang"}}).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, member, map[string]interface{}{"tags": []string{"go", "lang"}}).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", path, member, nil).Code)
+
+	var feed ResponseData[activityPage]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/activity", owner, nil), &feed))
+	assert.Equal(t, int64(3), feed.Data.Total)
+	assert.Equal(t, []string{"link.delete", "link.update", "link.create"},
+		[]string{feed.Data.Events[0].Action, feed.Data.Events[1].Action, feed.Data.Events[2].Action})
+
+	// Le diff ne contient que les champs modifiés
+	update := feed.Data.Events[1]
+	assert.Equal(t, "member@example.com", update.ActorEmail)
+	var before, after map[string]interface{}
+	assert.NoError(t, json.Unmarshal(update.Before, &before))
+	assert.NoError(t, json.Unmarshal(update.After, &after))
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"go"}}, before)
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"go", "lang"}}, after)
+	assert.Nil(t, feed.Data.Events[0].After)
+
+	// Filtres
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/activity?action=link.update", member, nil), &feed))
+	assert.Equal(t, int64(1), feed.Data.Total)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/activity?action=link.*&per_page=2", member, nil), &feed))
+	assert.Equal(t, int64(3), feed.Data.Total)
+	assert.Len(t, feed.Data.Events, 2)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/activity?from=2100-01-01T00:00:00Z", member, nil), &feed))
+	assert.Empty(t, feed.Data.Events)
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, "GET", "/activity?from=yesterday", member, nil).Code)
+
+	// Les autres utilisateurs ne voient rien
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/activity", outsider, nil), &feed))
+	assert.Empty(t, feed.Data.Events)
+
+	// Le journal d'audit est réservé aux admins
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "GET", "/admin/audit", owner, nil).Code)
+	assert.NoError(t, db.DB.Model(&users[2]).Update("role", models.RoleAdmin).Error)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", fmt.Sprintf("/admin/audit?actor_id=%d&target_type=link", users[1].ID), outsider, nil), &feed))
+	assert.Equal(t, int64(2), feed.Data.Total)
+}
+
+func TestAuditEventsAreAppendOnly(t *testing.T) {
+	db.SetupTestDB()
+
+	event := models.AuditEvent{Action: "link.create", TargetType: "link", TargetID: "1"}
+	assert.NoError(t, db.DB.Create(&event).Error)
+	assert.ErrorIs(t, db.DB.Model(&event).Update("action", "link.delete").Error, models.ErrAppendOnly)
+	assert.ErrorIs(t, db.DB.Delete(&event).Error, models.ErrAppendOnly)
+}
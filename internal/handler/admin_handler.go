@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UnlockInput struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+type ResetPasswordInput struct {
+	Password string `json:"password" binding:"omitempty,min=6"` // généré si absent
+}
+
+type SetRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// AdminUserView is the user as seen by admins, without secrets
+type AdminUserView struct {
+	ID               uint       `json:"id"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	EmailVerified    bool       `json:"email_verified"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	DisabledAt       *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LinkCount        int64      `json:"link_count"`
+}
+
+func adminUserView(user models.User, linkCount int64) AdminUserView {
+	return AdminUserView{
+		ID:               user.ID,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerified:    user.IsVerified(),
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		DisabledAt:       user.DisabledAt,
+		CreatedAt:        user.CreatedAt,
+		LinkCount:        linkCount,
+	}
+}
+
+// linkCounts returns the number of links of each user
+func linkCounts(userIDs []uint) map[uint]int64 {
+	var rows []struct {
+		UserID uint
+		Count  int64
+	}
+	db.DB.Model(&models.Link{}).Select("user_id, COUNT(*) AS count").
+		Where("user_id IN ?", userIDs).Group("user_id").Scan(&rows)
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts
+}
+
+// adminTarget loads the user of the :id parameter
+func adminTarget(c *gin.Context) (models.User, bool) {
+	var user models.User
+	if err := db.DB.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "User not found")
+		return user, false
+	}
+	return user, true
+}
+
+// recordAdminAction audits an action of the logged-in admin on a user
+func recordAdminAction(c *gin.Context, action string, target models.User, details interface{}) {
+	event := models.AuditEvent{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		IP:         c.ClientIP(),
+	}
+	if admin, ok := currentUser(c); ok {
+		event.ActorID = &admin.ID
+	}
+	audit.Record(event, details)
+}
+
+// PromoteAdmins gives the admin role to the existing accounts listed, used to bootstrap an instance
+func PromoteAdmins(emails []string) {
+	if len(emails) == 0 {
+		return
+	}
+	if err := db.DB.Model(&models.User{}).Where("email IN ?", emails).Update("role", models.RoleAdmin).Error; err != nil {
+		logger.ErrorLogger.Println("Failed to promote admins:", err)
+	}
+}
+
+// ListUsersHandler lists users, optionally searched by email (?q=), with their link counts
+func ListUsersHandler(c *gin.Context) {
+	page, perPage := pagination(c)
+
+	query := db.DB.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where(`LOWER(email) LIKE ? ESCAPE '\'`, likePattern(q))
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Order("id").Offset((page - 1) * perPage).Limit(perPage).Find(&users).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch users")
+		return
+	}
+
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	counts := linkCounts(ids)
+
+	views := make([]AdminUserView, len(users))
+	for i, u := range users {
+		views[i] = adminUserView(u, counts[u.ID])
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"users":    views,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// GetUserHandler returns one user with their link count
+func GetUserHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, adminUserView(user, linkCounts([]uint{user.ID})[user.ID]))
+}
+
+// DisableUserHandler blocks the login and revokes the sessions of a user
+func DisableUserHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	if admin, _ := currentUser(c); admin.ID == user.ID {
+		ErrorResponse(c, http.StatusBadRequest, "You cannot disable your own account")
+		return
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"disabled_at":     now,
+		"session_version": gorm.Expr("session_version + 1"),
+	}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not disable user")
+		return
+	}
+	recordAdminAction(c, "admin.user_disable", user, nil)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "User disabled"})
+}
+
+// EnableUserHandler reactivates a disabled user
+func EnableUserHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Model(&user).Update("disabled_at", nil).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not enable user")
+		return
+	}
+	recordAdminAction(c, "admin.user_enable", user, nil)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "User enabled"})
+}
+
+// DeleteUserHandler permanently deletes a user and all their data
+func DeleteUserHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	if admin, _ := currentUser(c); admin.ID == user.ID {
+		ErrorResponse(c, http.StatusBadRequest, "Use DELETE /account to delete your own account")
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteAccount(tx, user)
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete user")
+		return
+	}
+	recordAdminAction(c, "admin.user_delete", user, gin.H{"email": user.Email})
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// ResetPasswordHandler sets a new password (random if not given) and revokes the sessions of the user
+func ResetPasswordHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	// Le corps est facultatif
+	var input ResetPasswordInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+			ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	password := input.Password
+	if password == "" {
+		generated, err := auth.RandomToken(12)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Could not generate password")
+			return
+		}
+		password = generated
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Error hashing password")
+		return
+	}
+
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"password":        hashedPassword,
+		"session_version": gorm.Expr("session_version + 1"),
+	}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not reset password")
+		return
+	}
+	throttle.Reset(throttle.AccountKey(user.Email))
+	recordAdminAction(c, "admin.password_reset", user, nil)
+
+	response := gin.H{"message": "Password reset"}
+	if input.Password == "" {
+		response["password"] = password
+	}
+	SuccessResponse(c, http.StatusOK, response)
+}
+
+// SetRoleHandler changes the role of a user
+func SetRoleHandler(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var input SetRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if admin, _ := currentUser(c); admin.ID == user.ID && input.Role != models.RoleAdmin {
+		ErrorResponse(c, http.StatusBadRequest, "You cannot remove your own admin role")
+		return
+	}
+
+	if err := db.DB.Model(&user).Update("role", input.Role).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update role")
+		return
+	}
+	recordAdminAction(c, "admin.role_change", user, gin.H{"role": input.Role})
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+// RefreshScrapingHandler re-fetches the metadata of every link, or of one user's links (?user_id=)
+func RefreshScrapingHandler(c *gin.Context) {
+	userID := c.Query("user_id")
+	scope := func() *gorm.DB {
+		query := db.DB.Model(&models.Link{})
+		if userID != "" {
+			query = query.Where("user_id = ?", userID)
+		}
+		return query
+	}
+
+	var total int64
+	scope().Count(&total)
+
+	// Les liens sont parcourus par lots en arrière-plan, la file limite le nombre de requêtes simultanées
+	go func() {
+		var links []models.Link
+		scope().Select("id", "url").FindInBatches(&links, 500, func(tx *gorm.DB, batch int) error {
+			for _, link := range links {
+				waitScrape(link.ID, link.URL)
+			}
+			return nil
+		})
+	}()
+
+	if admin, ok := currentUser(c); ok {
+		audit.Record(models.AuditEvent{ActorID: &admin.ID, Action: "admin.scrape_refresh", IP: c.ClientIP()}, gin.H{"links": total})
+	}
+
+	SuccessResponse(c, http.StatusAccepted, gin.H{"queued": total})
+}
+
+// ListLockoutsHandler lists the emails and IPs currently blocked after failed logins
+func ListLockoutsHandler(c *gin.Context) {
+	throttles, err := throttle.Locked()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch lockouts")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, throttles)
+}
+
+// UnlockLoginHandler clears the failed login counters of an email and/or an IP
+func UnlockLoginHandler(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input UnlockInput
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Email == "" && input.IP == "") {
+		ErrorResponse(c, http.StatusBadRequest, "email or ip is required")
+		return
+	}
+
+	var keys []string
+	if input.Email != "" {
+		keys = append(keys, throttle.AccountKey(input.Email))
+	}
+	if input.IP != "" {
+		keys = append(keys, throttle.IPKey(input.IP))
+	}
+	cleared := throttle.Reset(keys...)
+
+	audit.Record(models.AuditEvent{
+		ActorID: &admin.ID,
+		Action:  "auth.unlock",
+		IP:      c.ClientIP(),
+	}, input)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"cleared": cleared})
+}
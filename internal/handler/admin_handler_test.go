@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/login", LoginUserHandler)
+	r.GET("/account", middleware.AuthRequired(), GetAccountHandler)
+	admin := r.Group("/admin", middleware.AuthRequired(), middleware.AdminRequired())
+	admin.GET("/users", ListUsersHandler)
+	admin.GET("/users/:id", GetUserHandler)
+	admin.POST("/users/:id/disable", DisableUserHandler)
+	admin.POST("/users/:id/enable", EnableUserHandler)
+	admin.POST("/users/:id/reset-password", ResetPasswordHandler)
+	admin.PUT("/users/:id/role", SetRoleHandler)
+	admin.DELETE("/users/:id", DeleteUserHandler)
+	return r
+}
+
+func createAdminAndUser(t *testing.T) (models.User, string, models.User, string) {
+	hashedPwd, _ := auth.HashPassword("password")
+	admin := models.User{Email: "admin@example.com", Password: hashedPwd, Role: models.RoleAdmin}
+	user := models.User{Email: "someone@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&admin).Error)
+	assert.NoError(t, db.DB.Create(&user).Error)
+
+	adminToken, _ := auth.CreateToken(admin)
+	userToken, _ := auth.CreateToken(user)
+	return admin, adminToken, user, userToken
+}
+
+func TestAdminRequiresRole(t *testing.T) {
+	db.SetupTestDB()
+	_, _, _, userToken := createAdminAndUser(t)
+
+	resp := sendJSON(adminRouter(), "GET", "/admin/users", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAdminListUsersWithLinkCounts(t *testing.T) {
+	db.SetupTestDB()
+	_, adminToken, user, _ := createAdminAndUser(t)
+
+	for i := 0; i < 3; i++ {
+		link := models.Link{URL: fmt.Sprintf("https://example.com/%d", i), Title: "Link", Tags: toJSON(nil), UserID: user.ID}
+		assert.NoError(t, db.DB.Create(&link).Error)
+	}
+
+	resp := sendJSON(adminRouter(), "GET", "/admin/users?q=SOMEONE", adminToken, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body ResponseData[struct {
+		Users []AdminUserView `json:"users"`
+		Total int64           `json:"total"`
+	}]
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.Equal(t, int64(1), body.Data.Total)
+	assert.Equal(t, "someone@example.com", body.Data.Users[0].Email)
+	assert.Equal(t, int64(3), body.Data.Users[0].LinkCount)
+	assert.NotContains(t, resp.Body.String(), "password")
+
+	// Les jokers de LIKE sont cherchés tels quels
+	for _, q := range []string{"_", "%25"} {
+		resp = sendJSON(adminRouter(), "GET", "/admin/users?q="+q, adminToken, nil)
+		assert.NoError(t, jsonDecode(resp, &body))
+		assert.Zero(t, body.Data.Total, q)
+	}
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	db.SetupTestDB()
+	_, adminToken, user, userToken := createAdminAndUser(t)
+	router := adminRouter()
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "POST", "/admin/users/0%20OR%201=1/disable", adminToken, nil).Code)
+	resp := sendJSON(router, "POST", fmt.Sprintf("/admin/users/%d/disable", user.ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Les sessions et le login sont bloqués
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(router, "GET", "/account", userToken, nil).Code)
+	resp = postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": "password"})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = sendJSON(router, "POST", fmt.Sprintf("/admin/users/%d/enable", user.ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": "password"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	db.SetupTestDB()
+	_, adminToken, user, _ := createAdminAndUser(t)
+	router := adminRouter()
+
+	resp := sendJSON(router, "POST", fmt.Sprintf("/admin/users/%d/reset-password", user.ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body ResponseData[map[string]string]
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.NotEmpty(t, body.Data["password"])
+
+	resp = postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": body.Data["password"]})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAdminDeleteAndRole(t *testing.T) {
+	db.SetupTestDB()
+	admin, adminToken, user, _ := createAdminAndUser(t)
+	router := adminRouter()
+
+	// Un admin ne peut pas se retirer ses propres droits
+	resp := sendJSON(router, "PUT", fmt.Sprintf("/admin/users/%d/role", admin.ID), adminToken, map[string]string{"role": "user"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = sendJSON(router, "PUT", fmt.Sprintf("/admin/users/%d/role", user.ID), adminToken, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = sendJSON(router, "DELETE", fmt.Sprintf("/admin/users/%d", user.ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", fmt.Sprintf("/admin/users/%d", user.ID), adminToken, nil).Code)
+}
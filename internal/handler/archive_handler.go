@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/exporter"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ArchiveReport sums up the restoration of an archive
+type ArchiveReport struct {
+	ImportReport
+	Collections int `json:"collections"` // collections créées
+}
+
+// userArchive gathers the personal links and the collections of the user
+func userArchive(user models.User) (exporter.Archive, error) {
+	archive := exporter.Archive{
+		Version:     exporter.ArchiveVersion,
+		ExportedAt:  time.Now().UTC(),
+		Email:       user.Email,
+		Collections: []exporter.ArchiveCollection{},
+		Links:       []exporter.ArchiveLink{},
+	}
+
+	var collections []models.Collection
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&collections).Error; err != nil {
+		return archive, err
+	}
+	for _, collection := range collections {
+		archive.Collections = append(archive.Collections, exporter.ArchiveCollection{
+			ID:          collection.ID,
+			Name:        collection.Name,
+			Description: collection.Description,
+			CreatedAt:   collection.CreatedAt,
+			UpdatedAt:   collection.UpdatedAt,
+		})
+	}
+
+	var links []models.Link
+	if err := db.DB.Where("user_id = ? AND workspace_id IS NULL", user.ID).Order("id").Find(&links).Error; err != nil {
+		return archive, err
+	}
+	for _, link := range links {
+		tags := linkTags(link)
+		if tags == nil {
+			tags = []string{}
+		}
+		archive.Links = append(archive.Links, exporter.ArchiveLink{
+			URL:          link.URL,
+			Title:        link.Title,
+			Tags:         tags,
+			Description:  link.Description,
+			Image:        link.Image,
+			Notes:        link.Notes,
+			ReadAt:       link.ReadAt,
+			ArchivedAt:   link.ArchivedAt,
+			CollectionID: link.CollectionID,
+			CreatedAt:    link.CreatedAt,
+			UpdatedAt:    link.UpdatedAt,
+		})
+	}
+	return archive, nil
+}
+
+// ExportArchiveHandler downloads everything the user owns: personal links with their tags,
+// metadata and notes, and collections. format=zip gives a zip instead of the JSON file.
+func ExportArchiveHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	archive, err := userArchive(user)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not export the vault")
+		return
+	}
+
+	name := "link-vault-" + archive.ExportedAt.Format("20060102")
+	switch c.Query("format") {
+	case "", "json":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, name))
+		c.JSON(http.StatusOK, archive)
+	case "zip":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+		c.Header("Content-Type", "application/zip")
+		c.Status(http.StatusOK)
+		exporter.WriteArchiveZip(c.Writer, archive)
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "format must be json or zip")
+	}
+}
+
+// restoreArchive saves the content of the archive for the user. Collections with the same
+// name are reused, links whose URL is already saved are skipped.
+func restoreArchive(tx *gorm.DB, user models.User, archive exporter.Archive) (ArchiveReport, error) {
+	report := ArchiveReport{ImportReport: ImportReport{Failures: []ImportFailure{}}}
+
+	// Les IDs de l'archive sont ceux de l'autre instance
+	collectionIDs := map[uint]uint{}
+	for _, collection := range archive.Collections {
+		var existing models.Collection
+		err := tx.Where("user_id = ? AND name = ?", user.ID, collection.Name).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			existing = models.Collection{Name: collection.Name, Description: collection.Description, UserID: user.ID}
+			existing.CreatedAt = collection.CreatedAt
+			err = tx.Create(&existing).Error
+			report.Collections++
+		}
+		if err != nil {
+			return report, err
+		}
+		collectionIDs[collection.ID] = existing.ID
+	}
+
+	saved, err := savedURLKeys(tx.Where("user_id = ? AND workspace_id IS NULL", user.ID))
+	if err != nil {
+		return report, err
+	}
+
+	for _, item := range archive.Links {
+		if !validBookmarkURL(item.URL) || item.Title == "" {
+			report.Failed++
+			report.Failures = append(report.Failures, ImportFailure{URL: item.URL, Title: item.Title, Reason: "Invalid link"})
+			continue
+		}
+		if saved[urlnorm.Normalize(item.URL)] {
+			report.Skipped++
+			continue
+		}
+
+		tagsJSON, _ := json.Marshal(item.Tags)
+		if item.Tags == nil {
+			tagsJSON = []byte("[]")
+		}
+		link := models.Link{
+			URL:         item.URL,
+			Title:       item.Title,
+			Tags:        tagsJSON,
+			Description: item.Description,
+			Image:       item.Image,
+			Notes:       item.Notes,
+			ReadAt:      item.ReadAt,
+			ArchivedAt:  item.ArchivedAt,
+			UserID:      user.ID,
+		}
+		link.CreatedAt = item.CreatedAt
+		if item.CollectionID != nil {
+			if id, ok := collectionIDs[*item.CollectionID]; ok {
+				link.CollectionID = &id
+			}
+		}
+		if err := tx.Create(&link).Error; err != nil {
+			return report, err
+		}
+		if err := saveRevision(tx, nil, link, user, nil); err != nil {
+			return report, err
+		}
+		saved[link.NormalizedURL] = true
+		report.Created++
+	}
+	return report, nil
+}
+
+// ImportArchiveHandler restores an archive of GET /export, from this instance or another one,
+// sent as the "file" field of a multipart form or as the request body. Nothing is saved if
+// the restoration fails.
+func ImportArchiveHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	file, ok := importFile(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Could not read the archive")
+		return
+	}
+	archive, err := exporter.ReadArchive(raw, int64(config.Int("IMPORT_MAX_SIZE_MB", 10))<<20)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid archive: "+err.Error())
+		return
+	}
+
+	var report ArchiveReport
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		report, err = restoreArchive(tx, user, archive)
+		return err
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not restore the archive")
+		return
+	}
+
+	audit.Change(models.AuditEvent{ActorID: &user.ID, OwnerID: &user.ID, Action: "link.import_archive", TargetType: "link", IP: c.ClientIP()},
+		nil, gin.H{"created": report.Created, "skipped": report.Skipped, "failed": report.Failed, "collections": report.Collections})
+
+	SuccessResponse(c, http.StatusOK, report)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/exporter"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func archiveRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/export", middleware.AuthRequired(), ExportArchiveHandler)
+	r.POST("/import/archive", middleware.AuthRequired(), ImportArchiveHandler)
+	return r
+}
+
+func TestExportAndRestoreArchive(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "old@example.com", "new@example.com")
+	router := archiveRouter()
+
+	// Une collection du nouveau compte prend l'ID de celle de l'ancien
+	assert.NoError(t, db.DB.Create(&models.Collection{Name: "Other", UserID: users[1].ID}).Error)
+	collection := models.Collection{Name: "Reading", UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+	readAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	created := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	link := models.Link{URL: "https://go.dev/", Title: "Go", Tags: []byte(`["go"]`), Notes: "Read the tour", ReadAt: &readAt, UserID: users[0].ID, CollectionID: &collection.ID}
+	link.CreatedAt = created
+	assert.NoError(t, db.DB.Create(&link).Error)
+	assert.NoError(t, db.DB.Create(&models.Link{URL: "https://ziglang.org/", Title: "Zig", Tags: []byte(`[]`), UserID: users[0].ID}).Error)
+
+	resp := sendJSON(router, "GET", "/export", tokens[0], nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var archive exporter.Archive
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &archive))
+	assert.Equal(t, exporter.ArchiveVersion, archive.Version)
+	assert.Len(t, archive.Collections, 1)
+	assert.Len(t, archive.Links, 2)
+
+	zipped := sendJSON(router, "GET", "/export?format=zip", tokens[0], nil)
+	assert.Equal(t, "application/zip", zipped.Header().Get("Content-Type"))
+
+	resp = uploadFile(router, "/import/archive", tokens[1], "vault.zip", zipped.Body.String())
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var report ResponseData[ArchiveReport]
+	assert.NoError(t, jsonDecode(resp, &report))
+	assert.Equal(t, 2, report.Data.Created)
+	assert.Equal(t, 1, report.Data.Collections)
+
+	var restored models.Link
+	assert.NoError(t, db.DB.Where("user_id = ? AND url = ?", users[1].ID, "https://go.dev/").First(&restored).Error)
+	var restoredCollection models.Collection
+	assert.NoError(t, db.DB.Where("user_id = ? AND name = ?", users[1].ID, "Reading").First(&restoredCollection).Error)
+	assert.Equal(t, restoredCollection.ID, *restored.CollectionID)
+	assert.Equal(t, "Read the tour", restored.Notes)
+	assert.Equal(t, readAt, restored.ReadAt.UTC())
+	assert.Equal(t, created, restored.CreatedAt.UTC())
+
+	// Restaurer deux fois ne crée pas de doublon
+	resp = uploadFile(router, "/import/archive", tokens[1], "vault.json", string(mustJSON(t, archive)))
+	assert.NoError(t, jsonDecode(resp, &report))
+	assert.Equal(t, 0, report.Data.Created)
+	assert.Equal(t, 2, report.Data.Skipped)
+	assert.Equal(t, 0, report.Data.Collections)
+
+	archive.Version = exporter.ArchiveVersion + 1
+	assert.Equal(t, http.StatusBadRequest, uploadFile(router, "/import/archive", tokens[1], "vault.json", string(mustJSON(t, archive))).Code)
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	raw, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return raw
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/dto"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// batchLimit caps the number of links of one batch
+const batchLimit = 100
+
+// Outcome of an entry of a batch
+const (
+	batchCreated = "created"
+	batchFailed  = "failed"
+	batchSkipped = "skipped" // entrée valide, non enregistrée car le lot a échoué
+)
+
+type BatchResult struct {
+	Index      int    `json:"index"`
+	Status     string `json:"status"`
+	ID         uint   `json:"id,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ExistingID uint   `json:"existing_id,omitempty"` // lien déjà enregistré avec la même URL
+}
+
+// BatchReport sums up a batch, the results are in the order of the entries
+type BatchReport struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
+// batchEntry is a valid entry waiting to be saved
+type batchEntry struct {
+	index int
+	link  models.Link
+}
+
+// validateBatch checks every entry on its own: fields, collection and duplicates, in the
+// library and within the batch. It returns the valid entries and a result per entry, the
+// valid ones still pending.
+func validateBatch(user models.User, workspaceID *uint, raw []json.RawMessage) ([]batchEntry, []BatchResult) {
+	results := make([]BatchResult, len(raw))
+	var entries []batchEntry
+	inBatch := map[string]int{}
+	for i, data := range raw {
+		results[i] = BatchResult{Index: i, Status: batchFailed}
+
+		var input dto.CreateLinkDTO
+		if err := json.Unmarshal(data, &input); err != nil {
+			results[i].Error = "Invalid entry"
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&input); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if input.CollectionID != nil && (workspaceID != nil || !ownsCollection(user.ID, *input.CollectionID)) {
+			results[i].Error = "Collection not found"
+			continue
+		}
+
+		key := urlnorm.Normalize(input.URL)
+		if first, ok := inBatch[key]; ok {
+			results[i].Error = fmt.Sprintf("Same link as entry %d", first)
+			continue
+		}
+		library := libraryOf(models.Link{UserID: user.ID, WorkspaceID: workspaceID})
+		if existing, found := duplicateOf(library, input.URL, 0); found {
+			results[i].Error = "Link already saved"
+			results[i].ExistingID = existing.ID
+			continue
+		}
+
+		inBatch[key] = i
+		results[i].Status = batchSkipped
+		entries = append(entries, batchEntry{index: i, link: linkFromInput(input, user, workspaceID)})
+	}
+	return entries, results
+}
+
+// CreateLinksBatchHandler saves an array of links, like POST /links for each of them, for
+// the browser extensions saving many tabs at once. By default the batch is all or nothing:
+// one invalid entry and no link is saved. With partial=true the valid entries are saved.
+func CreateLinksBatchHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	_, workspaceID, ok := linkScope(c, user, accessEdit)
+	if !ok {
+		return
+	}
+	partial := c.Query("partial") == "true"
+
+	var raw []json.RawMessage
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "The body must be an array of links")
+		return
+	}
+	if len(raw) == 0 || len(raw) > batchLimit {
+		ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A batch has 1 to %d links", batchLimit))
+		return
+	}
+
+	entries, results := validateBatch(user, workspaceID, raw)
+	report := BatchReport{Results: results}
+	if !partial && len(entries) < len(raw) {
+		report.Failed = len(raw) - len(entries)
+		c.JSON(http.StatusBadRequest, Response{Success: false, Error: "Invalid entries, no link was saved", Data: report})
+		return
+	}
+
+	var created []models.Link
+	if partial {
+		// Chaque lien dans sa transaction : un échec n'annule pas les autres
+		for _, entry := range entries {
+			link := entry.link
+			if err := db.DB.Transaction(func(tx *gorm.DB) error {
+				return createLink(tx, &link, user)
+			}); err != nil {
+				report.Results[entry.index].Status = batchFailed
+				report.Results[entry.index].Error = "Could not save the link"
+				// Enregistré entre-temps par une autre requête
+				if existing, found := duplicateOf(libraryOf(link), link.URL, 0); found {
+					report.Results[entry.index].Error = "Link already saved"
+					report.Results[entry.index].ExistingID = existing.ID
+				}
+				continue
+			}
+			report.Results[entry.index].Status = batchCreated
+			report.Results[entry.index].ID = link.ID
+			created = append(created, link)
+		}
+	} else {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			for i := range entries {
+				if err := createLink(tx, &entries[i].link, user); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// Une autre requête a pu enregistrer une des URL entre-temps
+			if entries, results := validateBatch(user, workspaceID, raw); len(entries) < len(raw) {
+				report = BatchReport{Failed: len(raw) - len(entries), Results: results}
+				c.JSON(http.StatusBadRequest, Response{Success: false, Error: "Invalid entries, no link was saved", Data: report})
+				return
+			}
+			ErrorResponse(c, http.StatusInternalServerError, "Could not save the links, none was saved")
+			return
+		}
+		for _, entry := range entries {
+			report.Results[entry.index].Status = batchCreated
+			report.Results[entry.index].ID = entry.link.ID
+			created = append(created, entry.link)
+		}
+	}
+
+	for i := range created {
+		recordLinkChange(c, user, "link.create", nil, &created[i])
+		enqueueScrape(created[i].ID, created[i].URL)
+	}
+
+	report.Created = len(created)
+	report.Failed = len(raw) - len(created)
+	status := http.StatusCreated
+	if report.Created == 0 {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, Response{Success: report.Created > 0, Data: report})
+}
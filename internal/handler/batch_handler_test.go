@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func batchRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/links/batch", middleware.AuthRequired(), CreateLinksBatchHandler)
+	return r
+}
+
+func TestCreateLinksBatch(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "batch@example.com")
+	token := tokens[0]
+	router := batchRouter()
+	existing := models.Link{URL: "https://go.dev/blog", Title: "Go blog", Tags: []byte(`[]`), UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&existing).Error)
+
+	batch := []interface{}{
+		map[string]interface{}{"url": "https://rust-lang.org", "title": "Rust", "tags": []string{"lang"}},
+		map[string]interface{}{"url": "http://go.dev/blog/?utm_source=tab", "title": "Go again"},
+		map[string]interface{}{"url": "not a url", "title": "Broken"},
+		map[string]interface{}{"url": "https://ziglang.org", "title": "Zig"},
+		map[string]interface{}{"url": "https://ziglang.org/", "title": "Zig twice"},
+	}
+
+	// Par défaut, une entrée invalide et rien n'est enregistré
+	resp := postJSON(router, "/links/batch", token, batch)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	var rejected ResponseData[BatchReport]
+	assert.NoError(t, jsonDecode(resp, &rejected))
+	assert.Equal(t, 0, rejected.Data.Created)
+	assert.Equal(t, 3, rejected.Data.Failed)
+	statuses := []string{}
+	for _, result := range rejected.Data.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []string{batchSkipped, batchFailed, batchFailed, batchSkipped, batchFailed}, statuses)
+	assert.Equal(t, existing.ID, rejected.Data.Results[1].ExistingID)
+	assert.Equal(t, "Same link as entry 3", rejected.Data.Results[4].Error)
+	var count int64
+	db.DB.Model(&models.Link{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// En mode partiel, les entrées valides sont enregistrées
+	resp = postJSON(router, "/links/batch?partial=true", token, batch)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var partial ResponseData[BatchReport]
+	assert.NoError(t, jsonDecode(resp, &partial))
+	assert.Equal(t, 2, partial.Data.Created)
+	assert.Equal(t, 3, partial.Data.Failed)
+	assert.Equal(t, batchCreated, partial.Data.Results[0].Status)
+	assert.Equal(t, batchCreated, partial.Data.Results[3].Status)
+
+	var rust models.Link
+	assert.NoError(t, db.DB.First(&rust, partial.Data.Results[0].ID).Error)
+	assert.Equal(t, "Rust", rust.Title)
+	assert.JSONEq(t, `["lang"]`, string(rust.Tags))
+	var revisions int64
+	db.DB.Model(&models.LinkRevision{}).Where("link_id = ?", rust.ID).Count(&revisions)
+	assert.Equal(t, int64(1), revisions)
+
+	// Un lot valide est enregistré d'un bloc
+	resp = postJSON(router, "/links/batch", token, []interface{}{
+		map[string]interface{}{"url": "https://python.org", "title": "Python"},
+		map[string]interface{}{"url": "https://kotlinlang.org", "title": "Kotlin"},
+	})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	db.DB.Model(&models.Link{}).Count(&count)
+	assert.Equal(t, int64(5), count)
+
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/batch", token, []interface{}{}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/batch", token, map[string]string{"url": "https://go.dev"}).Code)
+}
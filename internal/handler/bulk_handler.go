@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Actions of POST /links/bulk
+const (
+	bulkAddTags    = "add_tags"
+	bulkRemoveTags = "remove_tags"
+	bulkMove       = "move"
+	bulkMarkRead   = "mark_read"
+	bulkArchive    = "archive"
+	bulkDelete     = "delete"
+)
+
+// bulkLimit caps the number of links of one bulk operation
+const bulkLimit = 1000
+
+// Outcome of a bulk operation on a link
+const (
+	bulkUpdated   = "updated"
+	bulkUnchanged = "unchanged"
+	bulkDeleted   = "deleted"
+	bulkFailed    = "failed"
+)
+
+// BulkInput applies one action to the ids, or with all_matching to every link matching the
+// filters of GET /links given in the query string
+type BulkInput struct {
+	Action      string `json:"action" binding:"required,oneof=add_tags remove_tags move mark_read archive delete"`
+	IDs         []uint `json:"ids"`
+	AllMatching bool   `json:"all_matching"`
+
+	Tags         []string `json:"tags"`          // add_tags, remove_tags
+	CollectionID *uint    `json:"collection_id"` // move, 0 retire les liens de leur collection
+	Read         *bool    `json:"read"`          // mark_read, true par défaut
+	Archived     *bool    `json:"archived"`      // archive, true par défaut
+}
+
+type BulkResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkReport sums up a bulk operation, with the outcome of every link
+type BulkReport struct {
+	Action    string       `json:"action"`
+	Matched   int          `json:"matched"`
+	Updated   int          `json:"updated"`
+	Unchanged int          `json:"unchanged"`
+	Deleted   int          `json:"deleted"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+func (r *BulkReport) add(id uint, status, reason string) {
+	r.Results = append(r.Results, BulkResult{ID: id, Status: status, Error: reason})
+	switch status {
+	case bulkUpdated:
+		r.Updated++
+	case bulkUnchanged:
+		r.Unchanged++
+	case bulkDeleted:
+		r.Deleted++
+	case bulkFailed:
+		r.Failed++
+	}
+}
+
+// cleanTags trims the tags and removes the empty ones
+func cleanTags(tags []string) []string {
+	cleaned := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	return cleaned
+}
+
+// changeTags adds or removes the tags of the link, without duplicates and ignoring case.
+// It reports whether the tags changed.
+func changeTags(link *models.Link, tags []string, add bool) bool {
+	current := linkTags(*link)
+	has := map[string]bool{}
+	for _, tag := range current {
+		has[strings.ToLower(tag)] = true
+	}
+
+	changed := false
+	result := []string{}
+	if add {
+		result = append(result, current...)
+		for _, tag := range tags {
+			if !has[strings.ToLower(tag)] {
+				has[strings.ToLower(tag)] = true
+				result = append(result, tag)
+				changed = true
+			}
+		}
+	} else {
+		removed := map[string]bool{}
+		for _, tag := range tags {
+			removed[strings.ToLower(tag)] = true
+		}
+		for _, tag := range current {
+			if removed[strings.ToLower(tag)] {
+				changed = true
+				continue
+			}
+			result = append(result, tag)
+		}
+	}
+
+	if changed {
+		link.Tags, _ = json.Marshal(result)
+	}
+	return changed
+}
+
+// setDate sets or clears a date like read_at, keeping the first date. It reports whether the
+// date changed.
+func setDate(date **time.Time, set bool, now time.Time) bool {
+	if set == (*date != nil) {
+		return false
+	}
+	if set {
+		*date = &now
+	} else {
+		*date = nil
+	}
+	return true
+}
+
+// applyBulk changes the link for the action. It reports whether the link changed, or why the
+// action can't apply to it.
+func applyBulk(user models.User, link *models.Link, input BulkInput, now time.Time) (bool, string) {
+	switch input.Action {
+	case bulkAddTags:
+		return changeTags(link, input.Tags, true), ""
+	case bulkRemoveTags:
+		return changeTags(link, input.Tags, false), ""
+	case bulkMove:
+		// Les collections ne regroupent que les liens personnels de leur propriétaire
+		if link.WorkspaceID != nil || link.UserID != user.ID {
+			return false, "Only personal links can be in a collection"
+		}
+		if *input.CollectionID == 0 {
+			changed := link.CollectionID != nil
+			link.CollectionID = nil
+			return changed, ""
+		}
+		changed := link.CollectionID == nil || *link.CollectionID != *input.CollectionID
+		link.CollectionID = input.CollectionID
+		return changed, ""
+	case bulkMarkRead:
+		return setDate(&link.ReadAt, input.Read == nil || *input.Read, now), ""
+	case bulkArchive:
+		return setDate(&link.ArchivedAt, input.Archived == nil || *input.Archived, now), ""
+	}
+	return false, "Unknown action"
+}
+
+// bulkLinks loads the links of the bulk operation. Links given by id that the user can't edit
+// are reported as failed.
+func bulkLinks(c *gin.Context, user models.User, input BulkInput, report *BulkReport) ([]models.Link, bool) {
+	var links []models.Link
+	if input.AllMatching {
+		scope, _, ok := linkScope(c, user, accessEdit)
+		if !ok {
+			return nil, false
+		}
+		query, ok := filterLinks(c, scope)
+		if !ok {
+			return nil, false
+		}
+		if err := query.Limit(bulkLimit + 1).Find(&links).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+			return nil, false
+		}
+		if len(links) > bulkLimit {
+			ErrorResponse(c, http.StatusBadRequest, "Too many matching links, narrow the filters")
+			return nil, false
+		}
+		report.Matched = len(links)
+		return links, true
+	}
+
+	var found []models.Link
+	if err := db.DB.Where("id IN ?", input.IDs).Find(&found).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return nil, false
+	}
+	byID := map[uint]models.Link{}
+	for _, link := range found {
+		byID[link.ID] = link
+	}
+
+	seen := map[uint]bool{}
+	for _, id := range input.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		report.Matched++
+
+		link, ok := byID[id]
+		access := accessNone
+		if ok {
+			access = linkAccess(user, link)
+		}
+		switch {
+		case access == accessNone:
+			report.add(id, bulkFailed, "Link not found")
+		case access < accessEdit:
+			report.add(id, bulkFailed, "Read-only access")
+		default:
+			links = append(links, link)
+		}
+	}
+	return links, true
+}
+
+// BulkLinksHandler applies an action (add_tags, remove_tags, move, mark_read, archive or
+// delete) to a list of links in one transaction, and reports the outcome of every link
+func BulkLinksHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input BulkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.AllMatching == (len(input.IDs) > 0) {
+		ErrorResponse(c, http.StatusBadRequest, "Give either ids or all_matching")
+		return
+	}
+	if len(input.IDs) > bulkLimit {
+		ErrorResponse(c, http.StatusBadRequest, "Too many ids")
+		return
+	}
+	switch input.Action {
+	case bulkAddTags, bulkRemoveTags:
+		if input.Tags = cleanTags(input.Tags); len(input.Tags) == 0 {
+			ErrorResponse(c, http.StatusBadRequest, "tags is required")
+			return
+		}
+	case bulkMove:
+		if input.CollectionID == nil {
+			ErrorResponse(c, http.StatusBadRequest, "collection_id is required, 0 removes the links from their collection")
+			return
+		}
+		if *input.CollectionID != 0 && !ownsCollection(user.ID, *input.CollectionID) {
+			ErrorResponse(c, http.StatusBadRequest, "Collection not found")
+			return
+		}
+	}
+
+	report := BulkReport{Action: input.Action, Results: []BulkResult{}}
+	links, ok := bulkLinks(c, user, input, &report)
+	if !ok {
+		return
+	}
+
+	type change struct{ before, after models.Link }
+	var changes []change
+	now := time.Now()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, link := range links {
+			before := link
+			if input.Action == bulkDelete {
+				if err := tx.Delete(&link).Error; err != nil {
+					return err
+				}
+				changes = append(changes, change{before: before})
+				report.add(link.ID, bulkDeleted, "")
+				continue
+			}
+
+			changed, reason := applyBulk(user, &link, input, now)
+			switch {
+			case reason != "":
+				report.add(link.ID, bulkFailed, reason)
+				continue
+			case !changed:
+				report.add(link.ID, bulkUnchanged, "")
+				continue
+			}
+			if err := tx.Save(&link).Error; err != nil {
+				return err
+			}
+			if err := saveRevision(tx, &before, link, user, nil); err != nil {
+				return err
+			}
+			changes = append(changes, change{before: before, after: link})
+			report.add(link.ID, bulkUpdated, "")
+		}
+		return nil
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not apply the bulk operation, nothing was changed")
+		return
+	}
+
+	for i := range changes {
+		if input.Action == bulkDelete {
+			recordLinkChange(c, user, "link.delete", &changes[i].before, nil)
+		} else {
+			recordLinkChange(c, user, "link.update", &changes[i].before, &changes[i].after)
+		}
+	}
+	SuccessResponse(c, http.StatusOK, report)
+}
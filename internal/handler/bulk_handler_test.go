@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkRouter() *gin.Engine {
+	r := gin.Default()
+	r.GET("/links", middleware.AuthRequired(), GetLinksHandler)
+	r.POST("/links/bulk", middleware.AuthRequired(), BulkLinksHandler)
+	return r
+}
+
+func bulk(t *testing.T, router *gin.Engine, path, token string, payload interface{}) BulkReport {
+	resp := postJSON(router, path, token, payload)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var report ResponseData[BulkReport]
+	assert.NoError(t, jsonDecode(resp, &report))
+	return report.Data
+}
+
+func listedURLs(t *testing.T, router *gin.Engine, path, token string) []string {
+	var links ResponseData[[]models.Link]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path, token, nil), &links))
+	urls := []string{}
+	for _, link := range links.Data {
+		urls = append(urls, link.URL)
+	}
+	return urls
+}
+
+func TestBulkLinks(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "bulk@example.com", "neighbour@example.com")
+	owner, neighbour := users[0], users[1]
+	token := tokens[0]
+
+	links := []models.Link{
+		{URL: "https://go.dev", Title: "Go", Tags: []byte(`["go"]`), UserID: owner.ID},
+		{URL: "https://rust-lang.org", Title: "Rust", Tags: []byte(`["Lang"]`), UserID: owner.ID},
+		{URL: "https://ziglang.org", Title: "Zig", Tags: []byte(`[]`), UserID: owner.ID},
+		{URL: "https://example.com/private", Title: "Private", Tags: []byte(`[]`), UserID: neighbour.ID},
+		{URL: "https://example.com/shared", Title: "Shared", Tags: []byte(`[]`), UserID: neighbour.ID},
+	}
+	assert.NoError(t, db.DB.Create(&links).Error)
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: neighbour.ID, UserID: owner.ID, LinkID: &links[4].ID, Permission: models.PermissionView}).Error)
+	collection := models.Collection{Name: "Languages", UserID: owner.ID}
+	other := models.Collection{Name: "Not mine", UserID: neighbour.ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+	assert.NoError(t, db.DB.Create(&other).Error)
+
+	router := bulkRouter()
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/bulk", token, map[string]interface{}{"action": "add_tags", "tags": []string{"x"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/bulk", token, map[string]interface{}{"action": "add_tags", "ids": []uint{links[0].ID}}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/bulk", token, map[string]interface{}{"action": "rename", "ids": []uint{links[0].ID}}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/bulk", token, map[string]interface{}{"action": "move", "collection_id": other.ID, "ids": []uint{links[0].ID}}).Code)
+
+	report := bulk(t, router, "/links/bulk", token, map[string]interface{}{
+		"action": "add_tags",
+		"tags":   []string{"lang", "to-read"},
+		"ids":    []uint{links[0].ID, links[1].ID, links[3].ID, links[4].ID, links[0].ID},
+	})
+	assert.Equal(t, 4, report.Matched)
+	assert.Equal(t, 2, report.Updated)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, BulkResult{ID: links[3].ID, Status: "failed", Error: "Link not found"}, report.Results[0])
+	assert.Equal(t, BulkResult{ID: links[4].ID, Status: "failed", Error: "Read-only access"}, report.Results[1])
+	var rust models.Link
+	assert.NoError(t, db.DB.First(&rust, links[1].ID).Error)
+	assert.JSONEq(t, `["Lang","to-read"]`, string(rust.Tags))
+
+	// Les filtres de GET /links choisissent les liens
+	report = bulk(t, router, "/links/bulk?tag=to-read", token, map[string]interface{}{"action": "move", "collection_id": collection.ID, "all_matching": true})
+	assert.Equal(t, 2, report.Updated)
+	assert.Len(t, listedURLs(t, router, fmt.Sprintf("/links?collection_id=%d", collection.ID), token), 2)
+
+	report = bulk(t, router, "/links/bulk?tag=to-read", token, map[string]interface{}{"action": "remove_tags", "tags": []string{"TO-READ"}, "all_matching": true})
+	assert.Equal(t, 2, report.Updated)
+	assert.Empty(t, listedURLs(t, router, "/links?tag=to-read", token))
+
+	report = bulk(t, router, "/links/bulk", token, map[string]interface{}{"action": "mark_read", "ids": []uint{links[0].ID}})
+	assert.Equal(t, 1, report.Updated)
+	report = bulk(t, router, "/links/bulk", token, map[string]interface{}{"action": "mark_read", "ids": []uint{links[0].ID}})
+	assert.Equal(t, 1, report.Unchanged)
+
+	report = bulk(t, router, "/links/bulk?read=true", token, map[string]interface{}{"action": "archive", "all_matching": true})
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, []string{"https://rust-lang.org", "https://ziglang.org"}, listedURLs(t, router, "/links", token))
+	assert.Equal(t, []string{"https://go.dev"}, listedURLs(t, router, "/links?archived=true", token))
+	assert.Len(t, listedURLs(t, router, "/links?archived=all", token), 3)
+
+	report = bulk(t, router, "/links/bulk?q=zig", token, map[string]interface{}{"action": "delete", "all_matching": true})
+	assert.Equal(t, 1, report.Deleted)
+	assert.Equal(t, []string{"https://rust-lang.org"}, listedURLs(t, router, "/links", token))
+	var trashed models.Link
+	assert.NoError(t, db.DB.Unscoped().First(&trashed, links[2].ID).Error)
+	assert.True(t, trashed.DeletedAt.Valid)
+
+	// Un lien modifiable d'un autre reste dans sa collection
+	lent := models.Link{URL: "https://example.com/lent", Title: "Lent", Tags: []byte(`[]`), UserID: neighbour.ID, CollectionID: &other.ID}
+	assert.NoError(t, db.DB.Create(&lent).Error)
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: neighbour.ID, UserID: owner.ID, LinkID: &lent.ID, Permission: models.PermissionEdit}).Error)
+	report = bulk(t, router, "/links/bulk", token, map[string]interface{}{"action": "move", "collection_id": 0, "ids": []uint{lent.ID}})
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "Only personal links can be in a collection", report.Results[0].Error)
+	assert.NoError(t, db.DB.First(&lent, lent.ID).Error)
+	assert.Equal(t, other.ID, *lent.CollectionID)
+}
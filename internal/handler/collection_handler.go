@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/dto"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ownsCollection reports whether the collection exists and belongs to the user
+func ownsCollection(userID, collectionID uint) bool {
+	var count int64
+	db.DB.Model(&models.Collection{}).Where("id = ? AND user_id = ?", collectionID, userID).Count(&count)
+	return count > 0
+}
+
+// userCollection loads the collection of the URL, writing a 404 when it is not the user's
+func userCollection(c *gin.Context, user models.User) (models.Collection, bool) {
+	var collection models.Collection
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&collection).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Collection not found")
+		return collection, false
+	}
+	return collection, true
+}
+
+func CreateCollectionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input dto.CollectionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection := models.Collection{Name: input.Name, Description: input.Description, UserID: user.ID}
+	if err := db.DB.Create(&collection).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not save the collection")
+		return
+	}
+	recordCollectionChange(c, user, "collection.create", nil, &collection)
+
+	SuccessResponse(c, http.StatusCreated, collection)
+}
+
+func GetCollectionsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var collections []models.Collection
+	if err := db.DB.Where("user_id = ?", user.ID).Order("name").Find(&collections).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch collections")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, collections)
+}
+
+// GetCollectionHandler returns the collection with its links, to its owner or to the users it was granted to
+func GetCollectionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var collection models.Collection
+	if err := db.DB.Where("id = ?", c.Param("id")).First(&collection).Error; err != nil || collectionAccess(user, collection) == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	var links []models.Link
+	if err := db.DB.Where("collection_id = ?", collection.ID).Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return
+	}
+	withCommentCounts(links)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"collection": collection, "links": links})
+}
+
+func UpdateCollectionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	collection, ok := userCollection(c, user)
+	if !ok {
+		return
+	}
+
+	var input dto.CollectionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before := collection
+	collection.Name = input.Name
+	collection.Description = input.Description
+	if err := db.DB.Save(&collection).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update the collection")
+		return
+	}
+	recordCollectionChange(c, user, "collection.update", &before, &collection)
+
+	SuccessResponse(c, http.StatusOK, collection)
+}
+
+// DeleteCollectionHandler deletes the collection and its shares, its links are kept
+func DeleteCollectionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	collection, ok := userCollection(c, user)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Link{}).Where("collection_id = ?", collection.ID).Update("collection_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("collection_id = ?", collection.ID).Delete(&models.Grant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete collection")
+		return
+	}
+	recordCollectionChange(c, user, "collection.delete", &collection, nil)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
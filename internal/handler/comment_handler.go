@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentInput struct {
+	Body     string `json:"body" binding:"required,max=5000"`
+	ParentID *uint  `json:"parent_id"` // réponse à un autre commentaire du lien
+}
+
+type UpdateCommentInput struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+// CommentAuthor identifies the author or a mentioned user of a comment
+type CommentAuthor struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+// CommentView is a comment with its replies. Deleted comments that still have
+// replies are kept in the thread with an empty body.
+type CommentView struct {
+	ID        uint            `json:"id"`
+	ParentID  *uint           `json:"parent_id,omitempty"`
+	Author    *CommentAuthor  `json:"author,omitempty"`
+	Body      string          `json:"body"`
+	Mentions  []CommentAuthor `json:"mentions"`
+	Deleted   bool            `json:"deleted,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	EditedAt  *time.Time      `json:"edited_at,omitempty"`
+	Replies   []*CommentView  `json:"replies"`
+}
+
+// Une mention est un email précédé de @ : "@alice@example.com"
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([^\s@]+@[^\s@]+\.[^\s@.,;:!?)]+)`)
+
+// mentionedUsers returns the users mentioned in the body who can see the link.
+// Other addresses are ignored so mentions can't reveal a link to anyone else.
+func mentionedUsers(body string, link models.Link, author models.User) []models.User {
+	seen := map[string]bool{}
+	var users []models.User
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(match[1])
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		var user models.User
+		if err := db.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil || user.ID == author.ID {
+			continue
+		}
+		if linkAccess(user, link) >= accessView {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// saveMentions replaces the mentions of the comment and emails the newly mentioned users
+func saveMentions(comment models.Comment, link models.Link, author models.User) {
+	var previous []uint
+	db.DB.Model(&models.CommentMention{}).Where("comment_id = ?", comment.ID).Pluck("user_id", &previous)
+	alreadyMentioned := map[uint]bool{}
+	for _, id := range previous {
+		alreadyMentioned[id] = true
+	}
+
+	db.DB.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{})
+	for _, user := range mentionedUsers(comment.Body, link, author) {
+		db.DB.Create(&models.CommentMention{CommentID: comment.ID, UserID: user.ID})
+		if alreadyMentioned[user.ID] {
+			continue
+		}
+
+		err := mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: author.Email + " mentioned you on " + link.Title,
+			Body: author.Email + " mentioned you in a comment on " + link.Title + ":\n\n" + comment.Body + "\n\n" +
+				fmt.Sprintf("%s/links/%d", config.FrontendURL(), link.ID),
+		})
+		if err != nil {
+			logger.ErrorLogger.Println("Could not send mention email:", err)
+		}
+	}
+}
+
+// withCommentCounts sets CommentCount on the links with one query
+func withCommentCounts(links []models.Link) {
+	if len(links) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+
+	var counts []struct {
+		LinkID uint
+		Count  int64
+	}
+	db.DB.Model(&models.Comment{}).Select("link_id, COUNT(*) AS count").
+		Where("link_id IN ?", ids).Group("link_id").Scan(&counts)
+
+	byLink := map[uint]int64{}
+	for _, count := range counts {
+		byLink[count.LinkID] = count.Count
+	}
+	for i := range links {
+		links[i].CommentCount = byLink[links[i].ID]
+	}
+}
+
+// commentThreads builds the tree of the comments of a link
+func commentThreads(linkID uint) ([]*CommentView, error) {
+	var comments []models.Comment
+	if err := db.DB.Unscoped().Where("link_id = ?", linkID).Order("created_at, id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	commentIDs := make([]uint, 0, len(comments))
+	userIDs := []uint{}
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.ID)
+		userIDs = append(userIDs, comment.UserID)
+	}
+	var mentions []models.CommentMention
+	db.DB.Where("comment_id IN ?", commentIDs).Find(&mentions)
+	for _, mention := range mentions {
+		userIDs = append(userIDs, mention.UserID)
+	}
+
+	var users []models.User
+	db.DB.Select("id, email").Where("id IN ?", userIDs).Find(&users)
+	authors := map[uint]CommentAuthor{}
+	for _, user := range users {
+		authors[user.ID] = CommentAuthor{ID: user.ID, Email: user.Email}
+	}
+
+	views := map[uint]*CommentView{}
+	for _, comment := range comments {
+		view := &CommentView{
+			ID:        comment.ID,
+			ParentID:  comment.ParentID,
+			Body:      comment.Body,
+			Mentions:  []CommentAuthor{},
+			CreatedAt: comment.CreatedAt,
+			EditedAt:  comment.EditedAt,
+			Replies:   []*CommentView{},
+		}
+		if author, ok := authors[comment.UserID]; ok {
+			view.Author = &author
+		}
+		if comment.DeletedAt.Valid {
+			view.Deleted = true
+			view.Author = nil
+			view.Body = ""
+			view.EditedAt = nil
+		}
+		views[comment.ID] = view
+	}
+	for _, mention := range mentions {
+		if view, ok := views[mention.CommentID]; ok && !view.Deleted {
+			view.Mentions = append(view.Mentions, authors[mention.UserID])
+		}
+	}
+
+	roots := []*CommentView{}
+	for _, comment := range comments {
+		view := views[comment.ID]
+		if parent, ok := views[derefID(comment.ParentID)]; ok {
+			parent.Replies = append(parent.Replies, view)
+		} else {
+			roots = append(roots, view)
+		}
+	}
+	return pruneDeleted(roots), nil
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// pruneDeleted drops the deleted comments left without replies
+func pruneDeleted(views []*CommentView) []*CommentView {
+	kept := []*CommentView{}
+	for _, view := range views {
+		view.Replies = pruneDeleted(view.Replies)
+		if !view.Deleted || len(view.Replies) > 0 {
+			kept = append(kept, view)
+		}
+	}
+	return kept
+}
+
+// linkComment loads the comment of the URL on the link
+func linkComment(c *gin.Context, link models.Link) (models.Comment, bool) {
+	var comment models.Comment
+	if err := db.DB.Where("id = ? AND link_id = ?", c.Param("comment_id"), link.ID).First(&comment).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Comment not found")
+		return comment, false
+	}
+	return comment, true
+}
+
+// GetCommentsHandler returns the comment threads of a link, to anyone who can see it
+func GetCommentsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	threads, err := commentThreads(link.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch comments")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, threads)
+}
+
+// CreateCommentHandler adds a comment, or a reply with parent_id, to a link the user can see
+func CreateCommentHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	var input CommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.ParentID != nil {
+		var count int64
+		db.DB.Model(&models.Comment{}).Where("id = ? AND link_id = ?", *input.ParentID, link.ID).Count(&count)
+		if count == 0 {
+			ErrorResponse(c, http.StatusBadRequest, "Parent comment not found")
+			return
+		}
+	}
+
+	comment := models.Comment{LinkID: link.ID, UserID: user.ID, ParentID: input.ParentID, Body: input.Body}
+	if err := db.DB.Create(&comment).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not save the comment")
+		return
+	}
+	saveMentions(comment, link, user)
+
+	SuccessResponse(c, http.StatusCreated, commentView(comment, user))
+}
+
+// commentView is the view of a single comment, without its replies
+func commentView(comment models.Comment, author models.User) CommentView {
+	view := CommentView{
+		ID:        comment.ID,
+		ParentID:  comment.ParentID,
+		Author:    &CommentAuthor{ID: author.ID, Email: author.Email},
+		Body:      comment.Body,
+		Mentions:  []CommentAuthor{},
+		CreatedAt: comment.CreatedAt,
+		EditedAt:  comment.EditedAt,
+		Replies:   []*CommentView{},
+	}
+	db.DB.Table("comment_mentions").
+		Select("users.id, users.email").
+		Joins("JOIN users ON users.id = comment_mentions.user_id").
+		Where("comment_mentions.comment_id = ?", comment.ID).
+		Scan(&view.Mentions)
+	return view
+}
+
+// UpdateCommentHandler edits a comment, only its author can
+func UpdateCommentHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+	comment, ok := linkComment(c, link)
+	if !ok {
+		return
+	}
+	if comment.UserID != user.ID {
+		ErrorResponse(c, http.StatusForbidden, "Only the author can edit a comment")
+		return
+	}
+
+	var input UpdateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	comment.Body = input.Body
+	comment.EditedAt = &now
+	if err := db.DB.Save(&comment).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update the comment")
+		return
+	}
+	saveMentions(comment, link, user)
+
+	SuccessResponse(c, http.StatusOK, commentView(comment, user))
+}
+
+// DeleteCommentHandler deletes a comment. Its author and the users who can edit the link can.
+// Replies are kept.
+func DeleteCommentHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+	comment, ok := linkComment(c, link)
+	if !ok {
+		return
+	}
+	if comment.UserID != user.ID && linkAccess(user, link) < accessEdit {
+		ErrorResponse(c, http.StatusForbidden, "Only the author or an editor of the link can delete a comment")
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&comment).Error
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete the comment")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Comment deleted"})
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func commentRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/link/:id/comments", middleware.AuthRequired(), GetCommentsHandler)
+	r.POST("/link/:id/comments", middleware.AuthRequired(), CreateCommentHandler)
+	r.PUT("/link/:id/comments/:comment_id", middleware.AuthRequired(), UpdateCommentHandler)
+	r.DELETE("/link/:id/comments/:comment_id", middleware.AuthRequired(), DeleteCommentHandler)
+	return r
+}
+
+func TestCommentThreadsAndMentions(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "colleague@example.com", "outsider@example.com")
+	owner, colleague, outsider := tokens[0], tokens[1], tokens[2]
+
+	link := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`[]`), UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: users[0].ID, UserID: users[1].ID, LinkID: &link.ID, Permission: models.PermissionView}).Error)
+
+	router := commentRouter()
+	path := fmt.Sprintf("/link/%d/comments", link.ID)
+
+	assert.Equal(t, http.StatusNotFound, postJSON(router, path, outsider, map[string]string{"body": "Hello"}).Code)
+
+	// Seuls les utilisateurs qui voient le lien sont mentionnés
+	resp := postJSON(router, path, owner, map[string]string{"body": "What do you think @colleague@example.com? cc @outsider@example.com"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var root ResponseData[CommentView]
+	assert.NoError(t, jsonDecode(resp, &root))
+	assert.Equal(t, []CommentAuthor{{ID: users[1].ID, Email: "colleague@example.com"}}, root.Data.Mentions)
+	assert.Len(t, rec.sent, 1)
+	assert.Equal(t, "colleague@example.com", rec.sent[0].To)
+
+	// Un lecteur peut répondre
+	resp = postJSON(router, path, colleague, map[string]interface{}{"body": "Looks good", "parent_id": root.Data.ID})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var reply ResponseData[CommentView]
+	assert.NoError(t, jsonDecode(resp, &reply))
+
+	missing := uint(9999)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, path, colleague, map[string]interface{}{"body": "Lost", "parent_id": missing}).Code)
+
+	// Modifier le message ne renvoie pas la notification
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "PUT", fmt.Sprintf("%s/%d", path, root.Data.ID), colleague, map[string]string{"body": "Hacked"}).Code)
+	resp = sendJSON(router, "PUT", fmt.Sprintf("%s/%d", path, root.Data.ID), owner, map[string]string{"body": "Thoughts @colleague@example.com?"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, rec.sent, 1)
+
+	var threads ResponseData[[]CommentView]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path, colleague, nil), &threads))
+	assert.Len(t, threads.Data, 1)
+	assert.Equal(t, "Thoughts @colleague@example.com?", threads.Data[0].Body)
+	assert.NotNil(t, threads.Data[0].EditedAt)
+	assert.Len(t, threads.Data[0].Replies, 1)
+	assert.Equal(t, "Looks good", threads.Data[0].Replies[0].Body)
+	assert.Equal(t, "colleague@example.com", threads.Data[0].Replies[0].Author.Email)
+
+	// Le nombre de commentaires est renvoyé avec le lien
+	var got ResponseData[models.Link]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", fmt.Sprintf("/link/%d", link.ID), owner, nil), &got))
+	assert.Equal(t, int64(2), got.Data.CommentCount)
+
+	// Le commentaire supprimé reste dans le fil tant qu'il a des réponses
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("%s/%d", path, root.Data.ID), owner, nil).Code)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path, colleague, nil), &threads))
+	assert.Len(t, threads.Data, 1)
+	assert.True(t, threads.Data[0].Deleted)
+	assert.Empty(t, threads.Data[0].Body)
+	assert.Len(t, threads.Data[0].Replies, 1)
+
+	// Le propriétaire du lien peut modérer, la réponse partie, le fil disparaît
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("%s/%d", path, reply.Data.ID), owner, nil).Code)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path, colleague, nil), &threads))
+	assert.Empty(t, threads.Data)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser loads the user authenticated by middleware.AuthRequired.
+// It writes the error response itself and returns false on failure.
+func currentUser(c *gin.Context) (models.User, bool) {
+	user, err := middleware.CurrentUser(c)
+	switch err {
+	case nil:
+		return user, true
+	case middleware.ErrSessionRevoked:
+		ErrorResponse(c, http.StatusUnauthorized, "Session revoked")
+	case middleware.ErrAccountDisabled:
+		ErrorResponse(c, http.StatusForbidden, "Account disabled")
+	default:
+		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	}
+	return user, false
+}
+
+// pagination reads the page and per_page query parameters (50 per page by default, 200 at most)
+func pagination(c *gin.Context) (page, perPage int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ = strconv.Atoi(c.DefaultQuery("per_page", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 200 {
+		perPage = 50
+	}
+	return page, perPage
+}
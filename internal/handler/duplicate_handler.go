@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// libraryOf selects the links of the same library as the link: the personal links of its
+// owner, or the links of its workspace
+func libraryOf(link models.Link) *gorm.DB {
+	if link.WorkspaceID != nil {
+		return db.DB.Where("workspace_id = ?", *link.WorkspaceID)
+	}
+	return db.DB.Where("user_id = ? AND workspace_id IS NULL", link.UserID)
+}
+
+func sameLibrary(a, b models.Link) bool {
+	if a.WorkspaceID != nil || b.WorkspaceID != nil {
+		return a.WorkspaceID != nil && b.WorkspaceID != nil && *a.WorkspaceID == *b.WorkspaceID
+	}
+	return a.UserID == b.UserID
+}
+
+// duplicateOf finds a link of the scope, other than exceptID, with the same normalized URL or
+// whose page announced this URL as canonical
+func duplicateOf(scope *gorm.DB, rawURL string, exceptID uint) (models.Link, bool) {
+	key := urlnorm.Normalize(rawURL)
+	var existing models.Link
+	err := scope.Where("id <> ?", exceptID).
+		Where("normalized_url = ? OR canonical_url = ?", key, key).
+		Order("id").First(&existing).Error
+	return existing, err == nil
+}
+
+// duplicateResponse answers 409 with the link already saved
+func duplicateResponse(c *gin.Context, existing models.Link) {
+	c.JSON(http.StatusConflict, Response{
+		Success: false,
+		Error:   "Link already saved",
+		Data:    gin.H{"link": existing},
+	})
+}
+
+// saveFailed answers 409 when saving the link failed because another request saved the same
+// URL meanwhile, which the unique index catches, and 500 with the message otherwise
+func saveFailed(c *gin.Context, link models.Link, message string) {
+	if existing, found := duplicateOf(libraryOf(link), link.URL, link.ID); found {
+		duplicateResponse(c, existing)
+		return
+	}
+	ErrorResponse(c, http.StatusInternalServerError, message)
+}
+
+// savedURLKeys returns the normalized and canonical URLs of the links of the scope, for the
+// imports to skip the links already saved
+func savedURLKeys(scope *gorm.DB) (map[string]bool, error) {
+	var rows []struct {
+		NormalizedURL string
+		CanonicalURL  string
+	}
+	if err := scope.Model(&models.Link{}).Select("normalized_url, canonical_url").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for _, row := range rows {
+		keys[row.NormalizedURL] = true
+		if row.CanonicalURL != "" {
+			keys[row.CanonicalURL] = true
+		}
+	}
+	return keys, nil
+}
+
+// NormalizeLinkURLs fills the normalized URL of the links saved before duplicate detection
+func NormalizeLinkURLs() error {
+	var links []models.Link
+	return db.DB.Unscoped().Select("id, url").Where("normalized_url = '' OR normalized_url IS NULL").
+		FindInBatches(&links, 500, func(tx *gorm.DB, batch int) error {
+			for _, link := range links {
+				// UpdateColumn ne touche pas à updated_at
+				if err := db.DB.Unscoped().Model(&models.Link{}).Where("id = ?", link.ID).
+					UpdateColumn("normalized_url", urlnorm.Normalize(link.URL)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// MergeDuplicateLinks merges the links saved twice in a library before duplicate detection
+// into the oldest one, like MergeLinksHandler, so that db.UniqueLinkURLs can be created.
+// It returns the number of links moved to the trash.
+func MergeDuplicateLinks() (int, error) {
+	var keys []struct {
+		OwnerID       *uint
+		WorkspaceID   *uint
+		NormalizedURL string
+	}
+	if err := db.DB.Model(&models.Link{}).
+		Select("CASE WHEN workspace_id IS NULL THEN user_id END AS owner_id, workspace_id, normalized_url").
+		Group("1, 2, 3").Having("COUNT(*) > 1").Scan(&keys).Error; err != nil {
+		return 0, err
+	}
+
+	merged := 0
+	for _, key := range keys {
+		library := models.Link{WorkspaceID: key.WorkspaceID}
+		if key.OwnerID != nil {
+			library.UserID = *key.OwnerID
+		}
+		var links []models.Link
+		if err := libraryOf(library).Where("normalized_url = ?", key.NormalizedURL).Order("id").Find(&links).Error; err != nil {
+			return merged, err
+		}
+		if len(links) < 2 {
+			continue
+		}
+
+		kept, duplicates := links[0], links[1:]
+		ids := make([]uint, 0, len(duplicates))
+		for _, duplicate := range duplicates {
+			mergeInto(&kept, duplicate)
+			ids = append(ids, duplicate.ID)
+		}
+		if err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Comment{}).Where("link_id IN ?", ids).Update("link_id", kept.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Save(&kept).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&models.Link{}).Error
+		}); err != nil {
+			return merged, err
+		}
+		audit.Record(models.AuditEvent{
+			Action:      "link.merge",
+			TargetType:  "link",
+			TargetID:    strconv.FormatUint(uint64(kept.ID), 10),
+			OwnerID:     key.OwnerID,
+			WorkspaceID: key.WorkspaceID,
+		}, gin.H{"merged": ids})
+		merged += len(ids)
+	}
+	return merged, nil
+}
+
+// DuplicateGroup gathers links that are probably the same page
+type DuplicateGroup struct {
+	Key   string        `json:"key"`
+	Links []models.Link `json:"links"`
+}
+
+// GetDuplicatesHandler lists the groups of probable duplicates among the personal links, or
+// those of the workspace_id: same normalized URL, or a URL announced as canonical by another
+// link of the group
+func GetDuplicatesHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	scope, _, ok := linkScope(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	var links []models.Link
+	if err := scope.Order("id").Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return
+	}
+
+	// Union-find : deux liens partageant une clé sont dans le même groupe
+	parent := make([]int, len(links))
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	owners := map[string]int{}
+	for i, link := range links {
+		parent[i] = i
+		for _, key := range []string{link.NormalizedURL, link.CanonicalURL} {
+			if key == "" {
+				continue
+			}
+			if owner, ok := owners[key]; ok {
+				// Le plus ancien lien reste la racine du groupe
+				a, b := find(owner), find(i)
+				if a > b {
+					a, b = b, a
+				}
+				parent[b] = a
+			} else {
+				owners[key] = i
+			}
+		}
+	}
+
+	members := map[int][]models.Link{}
+	for i := range links {
+		root := find(i)
+		members[root] = append(members[root], links[i])
+	}
+	groups := []DuplicateGroup{}
+	for i, link := range links {
+		if group := members[i]; len(group) > 1 {
+			withCommentCounts(group)
+			groups = append(groups, DuplicateGroup{Key: link.NormalizedURL, Links: group})
+		}
+	}
+	SuccessResponse(c, http.StatusOK, groups)
+}
+
+type MergeLinksInput struct {
+	KeepID  uint   `json:"keep_id" binding:"required"`
+	LinkIDs []uint `json:"link_ids" binding:"required,min=1"`
+}
+
+// mergeInto adds the tags, notes and metadata of the duplicate to the kept link
+func mergeInto(kept *models.Link, duplicate models.Link) {
+	tags := linkTags(*kept)
+	if tags == nil {
+		tags = []string{}
+	}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		seen[strings.ToLower(tag)] = true
+	}
+	for _, tag := range linkTags(duplicate) {
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	kept.Tags, _ = json.Marshal(tags)
+
+	if notes := strings.TrimSpace(duplicate.Notes); notes != "" && !strings.Contains(kept.Notes, notes) {
+		if kept.Notes == "" {
+			kept.Notes = notes
+		} else {
+			kept.Notes += "\n\n" + notes
+		}
+	}
+	if kept.Description == "" {
+		kept.Description = duplicate.Description
+	}
+	if kept.Image == "" {
+		kept.Image = duplicate.Image
+	}
+	if kept.CollectionID == nil {
+		kept.CollectionID = duplicate.CollectionID
+	}
+	if duplicate.ReadAt != nil && (kept.ReadAt == nil || duplicate.ReadAt.Before(*kept.ReadAt)) {
+		kept.ReadAt = duplicate.ReadAt
+	}
+}
+
+// MergeLinksHandler merges duplicates into the keep_id link: their tags, notes and comments
+// move to it and they go to the trash. All the links must be editable and in the same library.
+func MergeLinksHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input MergeLinksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var kept models.Link
+	if err := db.DB.First(&kept, input.KeepID).Error; err != nil || linkAccess(user, kept) == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Link not found")
+		return
+	}
+	if linkAccess(user, kept) < accessEdit {
+		ErrorResponse(c, http.StatusForbidden, "Read-only access")
+		return
+	}
+
+	var duplicates []models.Link
+	picked := map[uint]bool{kept.ID: true}
+	for _, id := range input.LinkIDs {
+		if picked[id] {
+			continue
+		}
+		picked[id] = true
+		var duplicate models.Link
+		if err := db.DB.First(&duplicate, id).Error; err != nil || linkAccess(user, duplicate) == accessNone {
+			ErrorResponse(c, http.StatusNotFound, "Link not found")
+			return
+		}
+		if linkAccess(user, duplicate) < accessEdit {
+			ErrorResponse(c, http.StatusForbidden, "Read-only access")
+			return
+		}
+		if !sameLibrary(kept, duplicate) {
+			ErrorResponse(c, http.StatusBadRequest, "Only links of the same library can be merged")
+			return
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	if len(duplicates) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, "link_ids must contain other links than keep_id")
+		return
+	}
+
+	before := kept
+	ids := make([]uint, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		mergeInto(&kept, duplicate)
+		ids = append(ids, duplicate.ID)
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Comment{}).Where("link_id IN ?", ids).Update("link_id", kept.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&kept).Error; err != nil {
+			return err
+		}
+		if err := saveRevision(tx, &before, kept, user, nil); err != nil {
+			return err
+		}
+		// Dans la corbeille, la fusion reste réversible
+		return tx.Where("id IN ?", ids).Delete(&models.Link{}).Error
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not merge the links")
+		return
+	}
+
+	recordLinkChange(c, user, "link.merge", &before, &kept)
+	for i := range duplicates {
+		recordLinkChange(c, user, "link.delete", &duplicates[i], nil)
+	}
+
+	merged := []models.Link{kept}
+	withCommentCounts(merged)
+	SuccessResponse(c, http.StatusOK, merged[0])
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func duplicateRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/links", middleware.AuthRequired(), CreateLinkHandler)
+	r.PUT("/link/:id", middleware.AuthRequired(), UpdateLinkHandler)
+	r.GET("/links/duplicates", middleware.AuthRequired(), GetDuplicatesHandler)
+	r.POST("/links/merge", middleware.AuthRequired(), MergeLinksHandler)
+	return r
+}
+
+func TestDuplicateLinksRejected(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "dup@example.com", "other@example.com")
+	router := duplicateRouter()
+
+	resp := postJSON(router, "/links", tokens[0], map[string]interface{}{"url": "https://go.dev/blog/", "title": "Go blog"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var original models.Link
+	assert.NoError(t, db.DB.Where("user_id = ?", users[0].ID).First(&original).Error)
+	assert.Equal(t, "https://go.dev/blog", original.NormalizedURL)
+
+	resp = postJSON(router, "/links", tokens[0], map[string]interface{}{"url": "http://GO.dev/blog?utm_source=mail#top", "title": "Again"})
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	var conflict ResponseData[struct {
+		Link models.Link `json:"link"`
+	}]
+	assert.NoError(t, jsonDecode(resp, &conflict))
+	assert.Equal(t, original.ID, conflict.Data.Link.ID)
+
+	// Les bibliothèques des autres ne comptent pas
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", tokens[1], map[string]interface{}{"url": "https://go.dev/blog", "title": "Go blog"}).Code)
+
+	// Une page qui annonce cette URL comme canonique est aussi un doublon
+	assert.NoError(t, db.DB.Model(&original).UpdateColumn("canonical_url", "https://go.dev/blog/canonical").Error)
+	assert.Equal(t, http.StatusConflict, postJSON(router, "/links", tokens[0], map[string]interface{}{"url": "https://go.dev/blog/canonical/", "title": "Canonical"}).Code)
+
+	resp = postJSON(router, "/links", tokens[0], map[string]interface{}{"url": "https://go.dev/doc", "title": "Docs"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var docs models.Link
+	assert.NoError(t, db.DB.Where("user_id = ? AND url = ?", users[0].ID, "https://go.dev/doc").First(&docs).Error)
+	path := fmt.Sprintf("/link/%d", docs.ID)
+	assert.Equal(t, http.StatusConflict, sendJSON(router, "PUT", path, tokens[0], map[string]string{"url": "https://go.dev/blog?fbclid=1"}).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, tokens[0], map[string]string{"url": "https://go.dev/doc/"}).Code)
+
+	// Les liens d'avant la détection sont normalisés au démarrage
+	assert.NoError(t, db.DB.Model(&docs).UpdateColumn("normalized_url", "").Error)
+	assert.NoError(t, NormalizeLinkURLs())
+	assert.NoError(t, db.DB.First(&docs, docs.ID).Error)
+	assert.Equal(t, "https://go.dev/doc", docs.NormalizedURL)
+}
+
+func TestFindAndMergeDuplicates(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "merge@example.com", "stranger@example.com")
+	owner := users[0]
+
+	// Enregistrés avant la détection des doublons et son index unique
+	assert.NoError(t, db.DB.Exec("DROP INDEX idx_links_personal_url").Error)
+	links := []models.Link{
+		{URL: "https://go.dev/blog", Title: "Go blog", Tags: []byte(`["go"]`), UserID: owner.ID},
+		{URL: "http://go.dev/blog/?utm_campaign=x", Title: "Go blog again", Tags: []byte(`["Go","news"]`), Notes: "Read the release notes", UserID: owner.ID},
+		{URL: "https://blog.golang.org/", Title: "Old blog", Tags: []byte(`[]`), UserID: owner.ID, CanonicalURL: "https://go.dev/blog"},
+		{URL: "https://example.com", Title: "Unrelated", Tags: []byte(`[]`), UserID: owner.ID},
+	}
+	assert.NoError(t, db.DB.Create(&links).Error)
+	comment := models.Comment{LinkID: links[1].ID, UserID: owner.ID, Body: "Nice"}
+	assert.NoError(t, db.DB.Create(&comment).Error)
+
+	router := duplicateRouter()
+	var groups ResponseData[[]DuplicateGroup]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links/duplicates", tokens[0], nil), &groups))
+	assert.Len(t, groups.Data, 1)
+	assert.Equal(t, "https://go.dev/blog", groups.Data[0].Key)
+	assert.Len(t, groups.Data[0].Links, 3)
+
+	merge := map[string]interface{}{"keep_id": links[0].ID, "link_ids": []uint{links[1].ID, links[2].ID}}
+	assert.Equal(t, http.StatusNotFound, postJSON(router, "/links/merge", tokens[1], merge).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/links/merge", tokens[0], map[string]interface{}{"keep_id": links[0].ID, "link_ids": []uint{links[0].ID}}).Code)
+
+	resp := postJSON(router, "/links/merge", tokens[0], merge)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var kept ResponseData[models.Link]
+	assert.NoError(t, jsonDecode(resp, &kept))
+	assert.JSONEq(t, `["go","news"]`, string(kept.Data.Tags))
+	assert.Equal(t, "Read the release notes", kept.Data.Notes)
+	assert.Equal(t, int64(1), kept.Data.CommentCount)
+
+	// Les doublons fusionnés sont dans la corbeille
+	var trashed int64
+	db.DB.Unscoped().Model(&models.Link{}).Where("id IN ? AND deleted_at IS NOT NULL", []uint{links[1].ID, links[2].ID}).Count(&trashed)
+	assert.Equal(t, int64(2), trashed)
+
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links/duplicates", tokens[0], nil), &groups))
+	assert.Empty(t, groups.Data)
+	assert.NoError(t, db.UniqueLinkURLs())
+}
+
+func TestRestoredDuplicatesRejected(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "restore@example.com")
+	router := duplicateRouter()
+	router.DELETE("/link/:id", middleware.AuthRequired(), DeleteLinkHandler)
+	router.POST("/link/:id/restore", middleware.AuthRequired(), RestoreLinkHandler)
+	router.POST("/link/:id/revisions/:revision_id/restore", middleware.AuthRequired(), RestoreRevisionHandler)
+
+	create := func(url string) models.Link {
+		assert.Equal(t, http.StatusCreated, postJSON(router, "/links", tokens[0], map[string]interface{}{"url": url, "title": url}).Code)
+		var link models.Link
+		assert.NoError(t, db.DB.Where("url = ?", url).Order("id DESC").First(&link).Error)
+		return link
+	}
+
+	// Enregistré, supprimé, enregistré à nouveau : la restauration ferait un doublon
+	trashed := create("https://go.dev/blog")
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/link/%d", trashed.ID), tokens[0], nil).Code)
+	again := create("https://go.dev/blog")
+	resp := postJSON(router, fmt.Sprintf("/link/%d/restore", trashed.ID), tokens[0], nil)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Contains(t, resp.Body.String(), fmt.Sprintf(`"ID":%d`, again.ID))
+
+	// Revenir à une ancienne URL déjà enregistrée par un autre lien
+	docs := create("https://go.dev/doc")
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", fmt.Sprintf("/link/%d", docs.ID), tokens[0], map[string]string{"url": "https://go.dev/ref/spec"}).Code)
+	create("https://go.dev/doc/")
+	var revision models.LinkRevision
+	assert.NoError(t, db.DB.Where("link_id = ? AND url = ?", docs.ID, "https://go.dev/doc").First(&revision).Error)
+	assert.Equal(t, http.StatusConflict, postJSON(router, fmt.Sprintf("/link/%d/revisions/%d/restore", docs.ID, revision.ID), tokens[0], nil).Code)
+
+	// L'index unique arrête les doublons qui échapperaient aux vérifications
+	duplicate := models.Link{URL: "http://go.dev/blog/", Title: "Race", Tags: []byte(`[]`), UserID: users[0].ID}
+	assert.Error(t, db.DB.Create(&duplicate).Error)
+}
+
+func TestMergeDuplicateLinks(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, _ := workspaceUsers(t, "old@example.com")
+	workspace := models.Workspace{Name: "Team"}
+	assert.NoError(t, db.DB.Create(&workspace).Error)
+
+	// Des doublons d'avant l'index unique, dans une bibliothèque personnelle et un workspace
+	assert.NoError(t, db.DB.Exec("DROP INDEX idx_links_personal_url").Error)
+	assert.NoError(t, db.DB.Exec("DROP INDEX idx_links_workspace_url").Error)
+	links := []models.Link{
+		{URL: "https://go.dev", Title: "Go", Tags: []byte(`["go"]`), UserID: users[0].ID},
+		{URL: "https://go.dev/", Title: "Go again", Tags: []byte(`["lang"]`), UserID: users[0].ID},
+		{URL: "https://go.dev", Title: "Team Go", Tags: []byte(`[]`), UserID: users[0].ID, WorkspaceID: &workspace.ID},
+		{URL: "http://go.dev", Title: "Team Go again", Tags: []byte(`[]`), UserID: users[0].ID, WorkspaceID: &workspace.ID},
+		{URL: "https://rust-lang.org", Title: "Rust", Tags: []byte(`[]`), UserID: users[0].ID},
+	}
+	assert.NoError(t, db.DB.Create(&links).Error)
+
+	merged, err := MergeDuplicateLinks()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, merged)
+	var kept models.Link
+	assert.NoError(t, db.DB.First(&kept, links[0].ID).Error)
+	assert.JSONEq(t, `["go","lang"]`, string(kept.Tags))
+	var count int64
+	db.DB.Model(&models.Link{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	assert.NoError(t, db.UniqueLinkURLs())
+}
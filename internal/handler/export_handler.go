@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/exporter"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// linkTags decodes the tags of a link
+func linkTags(link models.Link) []string {
+	var tags []string
+	json.Unmarshal(link.Tags, &tags)
+	return tags
+}
+
+func exportBookmark(link models.Link) exporter.Bookmark {
+	return exporter.Bookmark{
+		ID:          link.ID,
+		URL:         link.URL,
+		Title:       link.Title,
+		Description: link.Description,
+		Notes:       link.Notes,
+		Tags:        linkTags(link),
+		AddedAt:     link.CreatedAt,
+		ModifiedAt:  link.UpdatedAt,
+		ReadAt:      link.ReadAt,
+	}
+}
+
+// foldersByCollection puts the links in the folder of their collection
+func foldersByCollection(userID uint, links []models.Link) ([]exporter.Folder, []exporter.Bookmark, error) {
+	var collections []models.Collection
+	if err := db.DB.Where("user_id = ?", userID).Order("name").Find(&collections).Error; err != nil {
+		return nil, nil, err
+	}
+
+	folders := make([]exporter.Folder, len(collections))
+	index := map[uint]int{}
+	for i, collection := range collections {
+		folders[i] = exporter.Folder{Name: collection.Name, AddedAt: collection.CreatedAt, ModifiedAt: collection.UpdatedAt}
+		index[collection.ID] = i
+	}
+
+	var loose []exporter.Bookmark
+	for _, link := range links {
+		if link.CollectionID != nil {
+			if i, ok := index[*link.CollectionID]; ok {
+				folders[i].Bookmarks = append(folders[i].Bookmarks, exportBookmark(link))
+				continue
+			}
+		}
+		loose = append(loose, exportBookmark(link))
+	}
+	return folders, loose, nil
+}
+
+// foldersByTag puts the links in the folder of each of their tags
+func foldersByTag(links []models.Link) ([]exporter.Folder, []exporter.Bookmark) {
+	byTag := map[string]*exporter.Folder{}
+	var loose []exporter.Bookmark
+	for _, link := range links {
+		bookmark := exportBookmark(link)
+		if len(bookmark.Tags) == 0 {
+			loose = append(loose, bookmark)
+			continue
+		}
+		for _, tag := range bookmark.Tags {
+			folder, ok := byTag[strings.ToLower(tag)]
+			if !ok {
+				folder = &exporter.Folder{Name: tag}
+				byTag[strings.ToLower(tag)] = folder
+			}
+			folder.Bookmarks = append(folder.Bookmarks, bookmark)
+		}
+	}
+
+	folders := make([]exporter.Folder, 0, len(byTag))
+	for _, folder := range byTag {
+		folders = append(folders, *folder)
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return strings.ToLower(folders[i].Name) < strings.ToLower(folders[j].Name)
+	})
+	return folders, loose
+}
+
+// ExportNetscapeHandler downloads the personal links, or those of the workspace_id, as a
+// bookmarks.html file. folders=collections or tags groups the links in folders, a link
+// with several tags is then in several folders.
+func ExportNetscapeHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	scope, workspaceID, ok := linkScope(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	var links []models.Link
+	if err := scope.Order("created_at").Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return
+	}
+
+	var folders []exporter.Folder
+	var loose []exporter.Bookmark
+	switch c.Query("folders") {
+	case "":
+		for _, link := range links {
+			loose = append(loose, exportBookmark(link))
+		}
+	case "tags":
+		folders, loose = foldersByTag(links)
+	case "collections":
+		if workspaceID != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Collections only group personal links")
+			return
+		}
+		var err error
+		if folders, loose, err = foldersByCollection(user.ID, links); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Could not fetch collections")
+			return
+		}
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "folders must be collections or tags")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="bookmarks.html"`)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	exporter.WriteNetscape(c.Writer, folders, loose)
+}
+
+// Formats of the link list, chosen by the format parameter or the Accept header
+const (
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatCSV      = "csv"
+	formatJSONFeed = "jsonfeed"
+)
+
+var linksFormats = map[string]string{
+	"application/json":      formatJSON,
+	"text/markdown":         formatMarkdown,
+	"text/csv":              formatCSV,
+	"application/feed+json": formatJSONFeed,
+}
+
+// linksFormat reads the format of the link list, JSON by default
+func linksFormat(c *gin.Context) (string, bool) {
+	if format := c.Query("format"); format != "" {
+		for _, known := range linksFormats {
+			if format == known {
+				return format, true
+			}
+		}
+		ErrorResponse(c, http.StatusBadRequest, "format must be json, markdown, csv or jsonfeed")
+		return "", false
+	}
+
+	// Le premier format proposé est choisi pour */* ou sans en-tête Accept
+	accepted := c.NegotiateFormat("application/json", "text/markdown", "text/csv", "application/feed+json")
+	if format, ok := linksFormats[accepted]; ok {
+		return format, true
+	}
+	return formatJSON, true
+}
+
+// writeLinks answers the links as Markdown grouped by tag, CSV or JSON Feed
+func writeLinks(c *gin.Context, format string, links []models.Link) {
+	bookmarks := make([]exporter.Bookmark, 0, len(links))
+	for _, link := range links {
+		bookmarks = append(bookmarks, exportBookmark(link))
+	}
+
+	c.Status(http.StatusOK)
+	switch format {
+	case formatMarkdown:
+		c.Header("Content-Type", "text/markdown; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="links.md"`)
+		exporter.WriteMarkdown(c.Writer, "Links", bookmarks)
+	case formatCSV:
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="links.csv"`)
+		exporter.WriteCSV(c.Writer, bookmarks)
+	case formatJSONFeed:
+		c.Header("Content-Type", "application/feed+json; charset=utf-8")
+		exporter.WriteJSONFeed(c.Writer, exporter.JSONFeed{Title: "Links", HomePageURL: config.FrontendURL()}, bookmarks)
+	}
+}
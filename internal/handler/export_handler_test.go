@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/exporter"
+	"github.com/DebroyeAntoine/go_link_vault/internal/importer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/export/netscape", middleware.AuthRequired(), ExportNetscapeHandler)
+	return r
+}
+
+func TestExportNetscape(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "other@example.com")
+	collection := models.Collection{Name: "Reading", UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+	for _, link := range []models.Link{
+		{URL: "https://go.dev/", Title: "Go", Tags: []byte(`["go","lang"]`), UserID: users[0].ID, CollectionID: &collection.ID},
+		{URL: "https://example.com/", Title: "Example", Tags: []byte(`[]`), UserID: users[0].ID},
+		{URL: "https://other.example.com/", Title: "Other", Tags: []byte(`[]`), UserID: users[1].ID},
+	} {
+		assert.NoError(t, db.DB.Create(&link).Error)
+	}
+
+	router := exportRouter()
+
+	resp := sendJSON(router, "GET", "/export/netscape", tokens[0], nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Disposition"), "bookmarks.html")
+	bookmarks, err := importer.ParseNetscape(strings.NewReader(resp.Body.String()))
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+	assert.Equal(t, []string{"go", "lang"}, bookmarks[0].Tags)
+	assert.False(t, bookmarks[0].AddedAt.IsZero())
+
+	resp = sendJSON(router, "GET", "/export/netscape?folders=collections", tokens[0], nil)
+	bookmarks, _ = importer.ParseNetscape(strings.NewReader(resp.Body.String()))
+	assert.Equal(t, []string{"Reading"}, bookmarks[0].Folders)
+	assert.Empty(t, bookmarks[1].Folders)
+
+	// Un lien est dans le dossier de chacun de ses tags
+	resp = sendJSON(router, "GET", "/export/netscape?folders=tags", tokens[0], nil)
+	bookmarks, _ = importer.ParseNetscape(strings.NewReader(resp.Body.String()))
+	assert.Len(t, bookmarks, 3)
+	assert.Equal(t, []string{"go"}, bookmarks[0].Folders)
+	assert.Equal(t, []string{"lang"}, bookmarks[1].Folders)
+
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, "GET", "/export/netscape?folders=dates", tokens[0], nil).Code)
+}
+
+func TestLinkFiltersAndFormats(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com")
+	collection := models.Collection{Name: "Reading", UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+	readAt := time.Now()
+	for i, link := range []models.Link{
+		{URL: "https://go.dev/", Title: "Go", Tags: []byte(`["go","lang"]`), CollectionID: &collection.ID},
+		{URL: "https://ziglang.org/", Title: "Zig", Tags: []byte(`["lang"]`), Notes: "100% fast", ReadAt: &readAt},
+		{URL: "https://example.com/", Title: "Example", Tags: []byte(`[]`)},
+	} {
+		link.UserID = users[0].ID
+		link.CreatedAt = time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)
+		assert.NoError(t, db.DB.Create(&link).Error)
+	}
+
+	router := workspaceRouter()
+	titles := func(query string) []string {
+		var links ResponseData[[]models.Link]
+		resp := sendJSON(router, "GET", "/links"+query, tokens[0], nil)
+		assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.NoError(t, jsonDecode(resp, &links))
+		var titles []string
+		for _, link := range links.Data {
+			titles = append(titles, link.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Go", "Zig", "Example"}, titles(""))
+	assert.Equal(t, []string{"Go", "Zig"}, titles("?tag=lang"))
+	assert.Equal(t, []string{"Go"}, titles("?tag=lang&tag=go"))
+	assert.Equal(t, []string{"Zig"}, titles("?q=100%25"))
+	assert.Equal(t, []string{"Go"}, titles(fmt.Sprintf("?collection_id=%d", collection.ID)))
+	assert.Equal(t, []string{"Zig", "Example"}, titles("?collection_id=0"))
+	assert.Equal(t, []string{"Zig"}, titles("?read=true"))
+	assert.Equal(t, []string{"Go", "Zig"}, titles("?to=2024-01-02T12:00:00Z"))
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, "GET", "/links?read=maybe", tokens[0], nil).Code)
+
+	// Les exports prennent les mêmes filtres
+	resp := sendJSON(router, "GET", "/links?tag=lang&format=markdown", tokens[0], nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/markdown")
+	assert.Equal(t, "# Links\n\n## go\n\n- [Go](https://go.dev/)\n\n## lang\n\n- [Go](https://go.dev/)\n- [Zig](https://ziglang.org/)\n", resp.Body.String())
+
+	req := jsonRequest("GET", "/links?read=false", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens[0])
+	req.Header.Set("Accept", "text/csv")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/csv")
+	assert.Equal(t, 3, strings.Count(resp.Body.String(), "\n"))
+
+	resp = sendJSON(router, "GET", "/links?format=jsonfeed", tokens[0], nil)
+	var feed exporter.JSONFeed
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &feed))
+	assert.Len(t, feed.Items, 3)
+
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, "GET", "/links?format=pdf", tokens[0], nil).Code)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/exporter"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// feedSize is the number of links of a feed, the most recent ones
+const feedSize = 50
+
+// CreateFeedTokenInput names the feed reader that will use the token
+type CreateFeedTokenInput struct {
+	Name string `json:"name" binding:"max=100"`
+}
+
+// FeedTokenView is a feed token as seen by its owner, the token is only returned at creation
+type FeedTokenView struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func feedTokenView(token models.FeedToken) FeedTokenView {
+	return FeedTokenView{ID: token.ID, Name: token.Name, CreatedAt: token.CreatedAt}
+}
+
+// CreateFeedTokenHandler creates the secret of the feed URLs of the user
+func CreateFeedTokenHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input CreateFeedTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create feed token")
+		return
+	}
+
+	feedToken := models.FeedToken{UserID: user.ID, TokenHash: auth.HashToken(token), Name: input.Name}
+	if err := db.DB.Create(&feedToken).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create feed token")
+		return
+	}
+
+	base := config.BaseURL() + "/feeds/" + token
+	SuccessResponse(c, http.StatusCreated, gin.H{
+		"feed_token": feedTokenView(feedToken),
+		"token":      token,
+		"url":        fmt.Sprintf("%s/users/%d", base, user.ID),
+	})
+}
+
+func GetFeedTokensHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var tokens []models.FeedToken
+	if err := db.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch feed tokens")
+		return
+	}
+
+	views := make([]FeedTokenView, 0, len(tokens))
+	for _, token := range tokens {
+		views = append(views, feedTokenView(token))
+	}
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+// DeleteFeedTokenHandler revokes a feed token, its URLs stop working immediately
+func DeleteFeedTokenHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Delete(&models.FeedToken{})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not revoke feed token")
+		return
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusNotFound, "Feed token not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Feed token revoked"})
+}
+
+// feedSubscriber returns the owner of the feed token of the URL. Unknown and revoked tokens,
+// and disabled accounts, answer 404.
+func feedSubscriber(c *gin.Context) (models.User, bool) {
+	var user models.User
+	var token models.FeedToken
+	if err := db.DB.Where("token_hash = ?", auth.HashToken(c.Param("token"))).First(&token).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Feed not found")
+		return user, false
+	}
+	if err := db.DB.First(&user, token.UserID).Error; err != nil || user.IsDisabled() {
+		ErrorResponse(c, http.StatusNotFound, "Feed not found")
+		return user, false
+	}
+	return user, true
+}
+
+// visibleLinks selects the links the user can see: their personal links, the links of their
+// workspaces and the links granted to them, directly or through a collection
+func visibleLinks(user models.User) *gorm.DB {
+	workspaces := db.DB.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", user.ID)
+	grantedLinks := db.DB.Model(&models.Grant{}).Select("link_id").Where("user_id = ? AND link_id IS NOT NULL", user.ID)
+	grantedCollections := db.DB.Model(&models.Grant{}).Select("collection_id").Where("user_id = ? AND collection_id IS NOT NULL", user.ID)
+	return db.DB.Where(
+		"(workspace_id IS NULL AND (user_id = ? OR id IN (?) OR collection_id IN (?))) OR workspace_id IN (?)",
+		user.ID, grantedLinks, grantedCollections, workspaces,
+	)
+}
+
+// writeFeed answers the most recent links of the query as Atom, or as RSS with format=rss
+func writeFeed(c *gin.Context, feed exporter.Feed, query *gorm.DB) {
+	write := exporter.WriteAtom
+	contentType := "application/atom+xml; charset=utf-8"
+	switch c.Query("format") {
+	case "", "atom":
+	case "rss":
+		write = exporter.WriteRSS
+		contentType = "application/rss+xml; charset=utf-8"
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "format must be atom or rss")
+		return
+	}
+
+	var links []models.Link
+	if err := query.Order("created_at DESC, id DESC").Limit(feedSize).Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return
+	}
+	bookmarks := make([]exporter.Bookmark, 0, len(links))
+	for _, link := range links {
+		bookmarks = append(bookmarks, exportBookmark(link))
+	}
+
+	feed.HomePageURL = config.FrontendURL()
+	feed.FeedURL = config.BaseURL() + c.Request.URL.RequestURI()
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	write(c.Writer, feed, bookmarks)
+}
+
+// UserFeedHandler serves the links saved by a user that the subscriber can see: all their
+// personal links for the subscriber themself, otherwise the links of shared workspaces and
+// the granted ones. The tag parameter, repeatable, keeps the links with these tags. Users
+// sharing nothing with the subscriber answer 404, so that the ids can't be enumerated.
+func UserFeedHandler(c *gin.Context) {
+	subscriber, ok := feedSubscriber(c)
+	if !ok {
+		return
+	}
+
+	var author models.User
+	if err := db.DB.Where("id = ?", c.Param("user_id")).First(&author).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if author.ID != subscriber.ID {
+		var shared int64
+		if err := visibleLinks(subscriber).Model(&models.Link{}).Where("user_id = ?", author.ID).Count(&shared).Error; err != nil || shared == 0 {
+			ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+	}
+
+	query := visibleLinks(subscriber).Where("user_id = ?", author.ID)
+	// Pas d'email dans le titre : le flux peut être lu par un agrégateur tiers
+	title := fmt.Sprintf("Links of user %d", author.ID)
+	if author.ID == subscriber.ID {
+		title = "My links"
+	}
+	for _, tag := range c.QueryArray("tag") {
+		query = query.Where(datatypes.JSONArrayQuery("tags").Contains(tag))
+		title += " #" + tag
+	}
+
+	writeFeed(c, exporter.Feed{ID: fmt.Sprintf("%s/feeds/users/%d", config.BaseURL(), author.ID), Title: title}, query)
+}
+
+// TagFeedHandler serves the links with the tag among those the subscriber can see
+func TagFeedHandler(c *gin.Context) {
+	subscriber, ok := feedSubscriber(c)
+	if !ok {
+		return
+	}
+
+	tag := c.Param("tag")
+	query := visibleLinks(subscriber).Where(datatypes.JSONArrayQuery("tags").Contains(tag))
+	feed := exporter.Feed{
+		// Le flux d'un tag dépend de l'abonné
+		ID:    fmt.Sprintf("%s/feeds/users/%d/tags/%s", config.BaseURL(), subscriber.ID, url.PathEscape(tag)),
+		Title: "Links tagged " + tag,
+	}
+	writeFeed(c, feed, query)
+}
+
+// CollectionFeedHandler serves the links of a collection of the subscriber or shared with them
+func CollectionFeedHandler(c *gin.Context) {
+	subscriber, ok := feedSubscriber(c)
+	if !ok {
+		return
+	}
+
+	var collection models.Collection
+	if err := db.DB.Where("id = ?", c.Param("id")).First(&collection).Error; err != nil || collectionAccess(subscriber, collection) == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	feed := exporter.Feed{
+		ID:    fmt.Sprintf("%s/feeds/collections/%d", config.BaseURL(), collection.ID),
+		Title: collection.Name,
+	}
+	writeFeed(c, feed, db.DB.Where("collection_id = ?", collection.ID))
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func feedRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/feed-tokens", middleware.AuthRequired(), CreateFeedTokenHandler)
+	r.GET("/feed-tokens", middleware.AuthRequired(), GetFeedTokensHandler)
+	r.DELETE("/feed-tokens/:id", middleware.AuthRequired(), DeleteFeedTokenHandler)
+	r.GET("/feeds/:token/users/:user_id", UserFeedHandler)
+	r.GET("/feeds/:token/tags/:tag", TagFeedHandler)
+	r.GET("/feeds/:token/collections/:id", CollectionFeedHandler)
+	return r
+}
+
+func openFeed(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestFeeds(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "alice@example.com", "bob@example.com")
+	alice, bob := users[0], users[1]
+
+	workspace := models.Workspace{Name: "Team"}
+	assert.NoError(t, db.DB.Create(&workspace).Error)
+	for _, user := range users {
+		assert.NoError(t, db.DB.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: models.WorkspaceEditor}).Error)
+	}
+	collection := models.Collection{Name: "Reading", UserID: alice.ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+
+	links := []models.Link{
+		{URL: "https://go.dev/private", Title: "Private go", Tags: []byte(`["go"]`), UserID: alice.ID},
+		{URL: "https://go.dev/team", Title: "Team go", Tags: []byte(`["go"]`), UserID: alice.ID, WorkspaceID: &workspace.ID},
+		{URL: "https://owasp.org", Title: "Team security", Tags: []byte(`["security"]`), UserID: alice.ID, WorkspaceID: &workspace.ID},
+		{URL: "https://go.dev/read", Title: "Read later", Tags: []byte(`[]`), UserID: alice.ID, CollectionID: &collection.ID},
+	}
+	assert.NoError(t, db.DB.Create(&links).Error)
+
+	router := feedRouter()
+	resp := postJSON(router, "/feed-tokens", tokens[1], map[string]string{"name": "Miniflux"})
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created ResponseData[struct {
+		FeedToken FeedTokenView `json:"feed_token"`
+		Token     string        `json:"token"`
+		URL       string        `json:"url"`
+	}]
+	assert.NoError(t, jsonDecode(resp, &created))
+	assert.True(t, strings.HasSuffix(created.Data.URL, fmt.Sprintf("/feeds/%s/users/%d", created.Data.Token, bob.ID)))
+	base := "/feeds/" + created.Data.Token
+
+	// Bob ne voit que les liens d'Alice qu'ils partagent
+	resp = openFeed(router, fmt.Sprintf("%s/users/%d?tag=go", base, alice.ID))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "application/atom+xml")
+	assert.Contains(t, resp.Body.String(), "https://go.dev/team")
+	assert.NotContains(t, resp.Body.String(), "https://go.dev/private")
+	assert.NotContains(t, resp.Body.String(), "https://owasp.org")
+
+	resp = openFeed(router, base+"/tags/security?format=rss")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "application/rss+xml")
+	assert.Contains(t, resp.Body.String(), "<rss version=\"2.0\">")
+	assert.Contains(t, resp.Body.String(), "https://owasp.org")
+	assert.Equal(t, http.StatusBadRequest, openFeed(router, base+"/tags/security?format=html").Code)
+
+	// La collection n'est visible qu'une fois partagée
+	collectionPath := fmt.Sprintf("%s/collections/%d", base, collection.ID)
+	assert.Equal(t, http.StatusNotFound, openFeed(router, collectionPath).Code)
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: alice.ID, UserID: bob.ID, CollectionID: &collection.ID, Permission: models.PermissionView}).Error)
+	resp = openFeed(router, collectionPath)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "https://go.dev/read")
+	resp = openFeed(router, fmt.Sprintf("%s/users/%d", base, alice.ID))
+	assert.Contains(t, resp.Body.String(), "https://go.dev/read")
+	assert.NotContains(t, resp.Body.String(), "https://go.dev/private")
+
+	assert.Equal(t, http.StatusNotFound, openFeed(router, fmt.Sprintf("/feeds/unknown/users/%d", alice.ID)).Code)
+	assert.NotContains(t, resp.Body.String(), alice.Email)
+
+	// Un utilisateur qui ne partage rien avec l'abonné n'existe pas pour lui
+	stranger := models.User{Email: "stranger@example.com", Password: "x"}
+	assert.NoError(t, db.DB.Create(&stranger).Error)
+	assert.Equal(t, http.StatusNotFound, openFeed(router, fmt.Sprintf("%s/users/%d", base, stranger.ID)).Code)
+	assert.Equal(t, http.StatusOK, openFeed(router, fmt.Sprintf("%s/users/%d", base, bob.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, openFeed(router, base+"/users/0%20OR%201=1").Code)
+	assert.Equal(t, http.StatusNotFound, openFeed(router, base+"/collections/0%20OR%201=1").Code)
+
+	var listed ResponseData[[]FeedTokenView]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/feed-tokens", tokens[1], nil), &listed))
+	assert.Len(t, listed.Data, 1)
+	assert.Equal(t, "Miniflux", listed.Data[0].Name)
+
+	tokenPath := fmt.Sprintf("/feed-tokens/%d", created.Data.FeedToken.ID)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "DELETE", tokenPath, tokens[0], nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", tokenPath, tokens[1], nil).Code)
+	assert.Equal(t, http.StatusNotFound, openFeed(router, base+"/tags/go").Code)
+}
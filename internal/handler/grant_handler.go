@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GrantInput gives a registered user access to one of link_id or collection_id
+type GrantInput struct {
+	Email        string `json:"email" binding:"required,email"`
+	LinkID       *uint  `json:"link_id"`
+	CollectionID *uint  `json:"collection_id"`
+	Permission   string `json:"permission" binding:"required,oneof=view edit"`
+}
+
+type GrantPermissionInput struct {
+	Permission string `json:"permission" binding:"required,oneof=view edit"`
+}
+
+// GrantView is a grant as seen by the owner of the item
+type GrantView struct {
+	ID           uint      `json:"id"`
+	Email        string    `json:"email"`
+	LinkID       *uint     `json:"link_id,omitempty"`
+	CollectionID *uint     `json:"collection_id,omitempty"`
+	Permission   string    `json:"permission"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SharedLinkView is a link shared with the current user
+type SharedLinkView struct {
+	Link       models.Link `json:"link"`
+	Permission string      `json:"permission"`
+	SharedBy   string      `json:"shared_by"`
+}
+
+// SharedCollectionView is a collection shared with the current user
+type SharedCollectionView struct {
+	Collection models.Collection `json:"collection"`
+	Permission string            `json:"permission"`
+	SharedBy   string            `json:"shared_by"`
+}
+
+// grantTarget checks that exactly one personal link or collection of the user is targeted
+func grantTarget(user models.User, input GrantInput) bool {
+	if (input.LinkID == nil) == (input.CollectionID == nil) {
+		return false
+	}
+	if input.CollectionID != nil {
+		return ownsCollection(user.ID, *input.CollectionID)
+	}
+
+	// Les liens de workspace se partagent en invitant dans le workspace
+	var count int64
+	db.DB.Model(&models.Link{}).Where("id = ? AND user_id = ? AND workspace_id IS NULL", *input.LinkID, user.ID).Count(&count)
+	return count > 0
+}
+
+// CreateGrantHandler gives another user access to a link or a collection.
+// Granting again the same item to the same user updates the permission.
+func CreateGrantHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input GrantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !grantTarget(user, input) {
+		ErrorResponse(c, http.StatusBadRequest, "Exactly one personal link_id or collection_id is required")
+		return
+	}
+
+	var grantee models.User
+	if err := db.DB.Where("LOWER(email) = LOWER(?)", input.Email).First(&grantee).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if grantee.ID == user.ID {
+		ErrorResponse(c, http.StatusBadRequest, "Cannot share with yourself")
+		return
+	}
+
+	grant := models.Grant{OwnerID: user.ID, UserID: grantee.ID}
+	query := db.DB.Where("user_id = ?", grantee.ID)
+	if input.LinkID != nil {
+		query = query.Where("link_id = ?", *input.LinkID)
+	} else {
+		query = query.Where("collection_id = ?", *input.CollectionID)
+	}
+	status := http.StatusOK
+	if err := query.First(&grant).Error; err != nil {
+		grant.LinkID = input.LinkID
+		grant.CollectionID = input.CollectionID
+		status = http.StatusCreated
+	}
+	grant.Permission = input.Permission
+	if err := db.DB.Save(&grant).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not save the grant")
+		return
+	}
+
+	SuccessResponse(c, status, GrantView{
+		ID:           grant.ID,
+		Email:        grantee.Email,
+		LinkID:       grant.LinkID,
+		CollectionID: grant.CollectionID,
+		Permission:   grant.Permission,
+		CreatedAt:    grant.CreatedAt,
+	})
+}
+
+// GetGrantsHandler lists the grants given by the current user
+func GetGrantsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	views := []GrantView{}
+	if err := db.DB.Table("grants").
+		Select("grants.id, users.email, grants.link_id, grants.collection_id, grants.permission, grants.created_at").
+		Joins("JOIN users ON users.id = grants.user_id").
+		Where("grants.owner_id = ? AND grants.deleted_at IS NULL", user.ID).
+		Order("grants.created_at DESC").
+		Scan(&views).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch grants")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+func UpdateGrantHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var grant models.Grant
+	if err := db.DB.Where("id = ? AND owner_id = ?", c.Param("id"), user.ID).First(&grant).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Grant not found")
+		return
+	}
+
+	var input GrantPermissionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := db.DB.Model(&grant).Update("permission", input.Permission).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update the grant")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"id": grant.ID, "permission": input.Permission})
+}
+
+// DeleteGrantHandler revokes a grant. The user it was given to can also remove it.
+func DeleteGrantHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result := db.DB.Unscoped().Where("id = ? AND (owner_id = ? OR user_id = ?)", c.Param("id"), user.ID, user.ID).Delete(&models.Grant{})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not revoke the grant")
+		return
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusNotFound, "Grant not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Grant revoked"})
+}
+
+// SharedWithMeHandler lists the links and collections other users granted to the current user
+func SharedWithMeHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var grants []models.Grant
+	if err := db.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&grants).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch shared items")
+		return
+	}
+
+	owners := map[uint]string{}
+	var ownerIDs []uint
+	for _, grant := range grants {
+		ownerIDs = append(ownerIDs, grant.OwnerID)
+	}
+	if len(ownerIDs) > 0 {
+		var users []models.User
+		db.DB.Select("id, email").Where("id IN ?", ownerIDs).Find(&users)
+		for _, owner := range users {
+			owners[owner.ID] = owner.Email
+		}
+	}
+
+	links := []SharedLinkView{}
+	collections := []SharedCollectionView{}
+	for _, grant := range grants {
+		if grant.LinkID != nil {
+			var link models.Link
+			if db.DB.First(&link, *grant.LinkID).Error == nil {
+				counted := []models.Link{link}
+				withCommentCounts(counted)
+				links = append(links, SharedLinkView{Link: counted[0], Permission: grant.Permission, SharedBy: owners[grant.OwnerID]})
+			}
+		}
+		if grant.CollectionID != nil {
+			var collection models.Collection
+			if db.DB.First(&collection, *grant.CollectionID).Error == nil {
+				collections = append(collections, SharedCollectionView{Collection: collection, Permission: grant.Permission, SharedBy: owners[grant.OwnerID]})
+			}
+		}
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"links": links, "collections": collections})
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func grantRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/collections/:id", middleware.AuthRequired(), GetCollectionHandler)
+	r.POST("/grants", middleware.AuthRequired(), CreateGrantHandler)
+	r.GET("/grants", middleware.AuthRequired(), GetGrantsHandler)
+	r.PUT("/grants/:id", middleware.AuthRequired(), UpdateGrantHandler)
+	r.DELETE("/grants/:id", middleware.AuthRequired(), DeleteGrantHandler)
+	r.GET("/shared-with-me", middleware.AuthRequired(), SharedWithMeHandler)
+	return r
+}
+
+func TestGrantLinkAccess(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "colleague@example.com", "outsider@example.com")
+	owner, colleague, outsider := tokens[0], tokens[1], tokens[2]
+
+	link := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`[]`), UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+	path := fmt.Sprintf("/link/%d", link.ID)
+
+	router := grantRouter()
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, colleague, nil).Code)
+
+	// Seul le propriétaire peut donner accès à son lien
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/grants", colleague, map[string]interface{}{"email": "outsider@example.com", "link_id": link.ID, "permission": "view"}).Code)
+	assert.Equal(t, http.StatusNotFound, postJSON(router, "/grants", owner, map[string]interface{}{"email": "nobody@example.com", "link_id": link.ID, "permission": "view"}).Code)
+
+	resp := postJSON(router, "/grants", owner, map[string]interface{}{"email": "colleague@example.com", "link_id": link.ID, "permission": "view"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var grant ResponseData[GrantView]
+	assert.NoError(t, jsonDecode(resp, &grant))
+
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", path, colleague, nil).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "PUT", path, colleague, map[string]string{"title": "Edited"}).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "DELETE", path, colleague, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, outsider, nil).Code)
+
+	var shared ResponseData[struct {
+		Links []SharedLinkView `json:"links"`
+	}]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/shared-with-me", colleague, nil), &shared))
+	assert.Len(t, shared.Data.Links, 1)
+	assert.Equal(t, "view", shared.Data.Links[0].Permission)
+	assert.Equal(t, "owner@example.com", shared.Data.Links[0].SharedBy)
+
+	// Le lien partagé ne se mélange pas aux liens personnels
+	var links ResponseData[[]models.Link]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links", colleague, nil), &links))
+	assert.Empty(t, links.Data)
+
+	grantPath := fmt.Sprintf("/grants/%d", grant.Data.ID)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "PUT", grantPath, colleague, map[string]string{"permission": "edit"}).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", grantPath, owner, map[string]string{"permission": "edit"}).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, colleague, map[string]string{"title": "Edited"}).Code)
+
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", grantPath, owner, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, colleague, nil).Code)
+}
+
+func TestGrantCollectionAccess(t *testing.T) {
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "colleague@example.com")
+	owner, colleague := tokens[0], tokens[1]
+
+	collection := models.Collection{Name: "Reading list", UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&collection).Error)
+	link := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`[]`), UserID: users[0].ID, CollectionID: &collection.ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+
+	router := grantRouter()
+	collectionPath := fmt.Sprintf("/collections/%d", collection.ID)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", "/collections/0%20OR%201=1", owner, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", collectionPath, colleague, nil).Code)
+
+	resp := postJSON(router, "/grants", owner, map[string]interface{}{"email": "colleague@example.com", "collection_id": collection.ID, "permission": "edit"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var grant ResponseData[GrantView]
+	assert.NoError(t, jsonDecode(resp, &grant))
+
+	// Modifier le lien ne permet pas de le sortir de la collection du propriétaire
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, "PUT", fmt.Sprintf("/link/%d", link.ID), colleague, map[string]interface{}{"collection_id": 0}).Code)
+	assert.NoError(t, db.DB.First(&link, link.ID).Error)
+	assert.Equal(t, collection.ID, *link.CollectionID)
+
+	// Le même partage met à jour la permission au lieu d'en créer un second
+	resp = postJSON(router, "/grants", owner, map[string]interface{}{"email": "colleague@example.com", "collection_id": collection.ID, "permission": "view"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var grants ResponseData[[]GrantView]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/grants", owner, nil), &grants))
+	assert.Len(t, grants.Data, 1)
+	assert.Equal(t, "view", grants.Data[0].Permission)
+
+	// L'accès à la collection s'étend à ses liens
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", collectionPath, colleague, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", fmt.Sprintf("/link/%d", link.ID), colleague, nil).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "DELETE", fmt.Sprintf("/link/%d", link.ID), colleague, nil).Code)
+
+	// Le bénéficiaire peut retirer le partage
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/grants/%d", grant.Data.ID), colleague, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", collectionPath, colleague, nil).Code)
+}
@@ -3,29 +3,52 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
 	"github.com/DebroyeAntoine/go_link_vault/internal/db"
 	"github.com/DebroyeAntoine/go_link_vault/internal/dto"
 	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
 	"github.com/DebroyeAntoine/go_link_vault/internal/models"
-	"github.com/DebroyeAntoine/go_link_vault/internal/scraper"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
 	"github.com/gin-gonic/gin"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
+// linkFromInput builds the link described by the input, personal or of the workspace
+func linkFromInput(input dto.CreateLinkDTO, user models.User, workspaceID *uint) models.Link {
+	tagsJSON, _ := json.Marshal(input.Tags)
+	return models.Link{
+		URL:          input.URL,
+		Title:        input.Title,
+		Tags:         datatypes.JSON(tagsJSON),
+		Notes:        input.Notes,
+		UserID:       user.ID,
+		CollectionID: input.CollectionID,
+		WorkspaceID:  workspaceID,
+	}
+}
+
+// createLink saves a new link with its first version
+func createLink(tx *gorm.DB, link *models.Link, user models.User) error {
+	if err := tx.Create(link).Error; err != nil {
+		return err
+	}
+	return saveRevision(tx, nil, *link, user, nil)
+}
+
 func CreateLinkHandler(c *gin.Context) {
-	// Extraire l'email du token
-	userEmail, exists := c.Get("userEmail")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	// Trouver l'utilisateur authentifié dans la DB
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	// Trouver l'utilisateur dans la DB
-	var user models.User
-	if err := db.DB.Where("email = ?", userEmail).First(&user).Error; err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	// Lien personnel, ou lien du workspace_id si l'utilisateur peut y écrire
+	scope, workspaceID, ok := linkScope(c, user, accessEdit)
+	if !ok {
 		return
 	}
 
@@ -35,60 +58,96 @@ func CreateLinkHandler(c *gin.Context) {
 		return
 	}
 
-	tagsJSON, _ := json.Marshal(input.Tags)
-	link := models.Link{
-		URL:    input.URL,
-		Title:  input.Title,
-		Tags:   datatypes.JSON(tagsJSON),
-		UserID: user.ID,
-	}
-	if err := db.DB.Create(&link).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "could not save the link")
+	// Les collections ne regroupent que des liens personnels
+	if input.CollectionID != nil && (workspaceID != nil || !ownsCollection(user.ID, *input.CollectionID)) {
+		ErrorResponse(c, http.StatusBadRequest, "Collection not found")
 		return
 	}
 
-	go func(linkID uint, url string) {
-		metadata, err := scraper.FetchMetadata(url)
-		if err != nil {
-			logger.ErrorLogger.Println("Failed to fetch metadata:", err)
-			return
-		}
+	// Une même page n'est enregistrée qu'une fois par bibliothèque
+	if existing, found := duplicateOf(scope, input.URL, 0); found {
+		duplicateResponse(c, existing)
+		return
+	}
+
+	link := linkFromInput(input, user, workspaceID)
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return createLink(tx, &link, user)
+	}); err != nil {
+		saveFailed(c, link, "could not save the link")
+		return
+	}
 
-		db.DB.Model(&models.Link{}).Where("id = ?", linkID).Updates(models.Link{
-			Description: metadata.Description,
-			Image:       metadata.Image,
-		})
-	}(link.ID, link.URL)
+	recordLinkChange(c, user, "link.create", nil, &link)
+	enqueueScrape(link.ID, link.URL)
 
 	SuccessResponse(c, http.StatusCreated, gin.H{
-		"url":   link.URL,
-		"title": link.Title,
-		"tags":  link.Tags,
+		"url":           link.URL,
+		"title":         link.Title,
+		"tags":          link.Tags,
+		"notes":         link.Notes,
+		"read_at":       link.ReadAt,
+		"collection_id": link.CollectionID,
+		"workspace_id":  link.WorkspaceID,
 	})
 }
 
+type RegisterInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// emailTaken reports whether another account than exceptID uses the email, ignoring case
+func emailTaken(email string, exceptID uint) bool {
+	var taken int64
+	db.DB.Model(&models.User{}).Where("lower(email) = lower(?) AND id <> ?", email, exceptID).Count(&taken)
+	return taken > 0
+}
+
 // Register handler
 func RegisterUserHandler(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var input RegisterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	input.Email = strings.TrimSpace(input.Email)
+
+	// Une adresse ne sert qu'à un compte, sinon on pourrait l'occuper avant son propriétaire
+	if emailTaken(input.Email, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already in use"})
+		return
+	}
 
 	// Hash password
-	hashedPassword, err := auth.HashPassword(user.Password)
+	hashedPassword, err := auth.HashPassword(input.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
 		return
 	}
-	user.Password = hashedPassword
+
+	// Les nouveaux comptes ne sont pas vérifiés tant que l'email n'est pas confirmé
+	user := models.User{
+		Email:    input.Email,
+		Password: hashedPassword,
+	}
 
 	// Save user to DB
 	if err := db.DB.Create(&user).Error; err != nil {
+		// L'index unique arrête deux inscriptions simultanées
+		if emailTaken(input.Email, 0) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already in use"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create user"})
 		return
 	}
 
+	// L'utilisateur pourra redemander un lien si l'envoi échoue
+	if err := sendVerificationEmail(user, user.Email); err != nil {
+		logger.ErrorLogger.Println("Failed to send verification email:", err)
+	}
+
 	// Generate JWT token
 	token, err := auth.CreateToken(user)
 	if err != nil {
@@ -111,72 +170,100 @@ func LoginUserHandler(c *gin.Context) {
 		return
 	}
 
+	// Compteurs d'échecs par compte et par IP
+	keys := loginKeys(c, input.Email)
+	if wait := throttle.Blocked(keys...); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
 	var dbUser models.User
-	if err := db.DB.Where("email = ?", input.Email).First(&dbUser).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if err := db.DB.Where("lower(email) = lower(?)", strings.TrimSpace(input.Email)).First(&dbUser).Error; err != nil {
+		auth.CheckDummyPassword(input.Password)
+		loginFailed(c, keys)
 		return
 	}
 
 	if !auth.CheckPasswordHash(input.Password, dbUser.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		loginFailed(c, keys)
 		return
 	}
 
-	token, err := auth.CreateToken(dbUser)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating JWT"})
-		return
+	// Avec la 2FA, le compteur n'est remis à zéro qu'une fois le code validé
+	if completeLogin(c, dbUser, cookieSessionRequested(c)) {
+		throttle.Reset(keys[0])
+	}
+}
+
+// completeLogin issues the session token, or a 2FA challenge when enabled. It reports
+// whether the session was issued.
+func completeLogin(c *gin.Context, user models.User, cookie bool) bool {
+	if user.IsDisabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	// Avec la 2FA, le token n'est émis qu'après validation du code sur /login/2fa
+	if user.TwoFactorEnabled {
+		challenge, err := createTwoFactorChallenge(user, cookie)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating two-factor challenge"})
+			return false
+		}
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge": challenge})
+		return false
+	}
+
+	return issueSession(c, user, cookie)
 }
 
 func GetLinksHandler(c *gin.Context) {
-	// Vérification du token JWT et récupération de l'ID de l'utilisateur
-	claims, err := auth.ValidateToken(c)
-	if err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+	// Récupération de l'utilisateur authentifié
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	// Récupération de l'email directement depuis les claims
-	email := claims.Issuer
+	// Liens personnels, ou ceux du workspace_id demandé
+	scope, _, ok := linkScope(c, user, accessView)
+	if !ok {
+		return
+	}
 
-	var user models.User
-	if err := db.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	// Mêmes filtres pour la liste et ses exports
+	query, ok := filterLinks(c, scope)
+	if !ok {
+		return
+	}
+	format, ok := linksFormat(c)
+	if !ok {
 		return
 	}
 
-	// Récupération de tous les liens de l'utilisateur
 	var links []models.Link /* No preload because useless and risky to return User */
-	if err := db.DB.Where("user_id = ?", user.ID).Find(&links).Error; err != nil {
+	if err := query.Find(&links).Error; err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
 		return
 	}
+	if format != formatJSON {
+		writeLinks(c, format, links)
+		return
+	}
+	withCommentCounts(links)
 
 	SuccessResponse(c, http.StatusOK, links)
 }
 
 func UpdateLinkHandler(c *gin.Context) {
-	claims, err := auth.ValidateToken(c)
-	if err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
 	// On récupère l'utilisateur
-	var user models.User
-	if err := db.DB.Where("email = ?", claims.Issuer).First(&user).Error; err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	// On récupère l'ID du lien depuis l'URL
-	linkID := c.Param("id")
-	var link models.Link
-	if err := db.DB.Where("id = ? AND user_id = ?", linkID, user.ID).First(&link).Error; err != nil {
-		ErrorResponse(c, http.StatusNotFound, "Link not found")
+	// On récupère le lien depuis l'URL, il doit être modifiable par l'utilisateur
+	link, ok := linkForRequest(c, user, accessEdit)
+	if !ok {
 		return
 	}
 
@@ -185,10 +272,17 @@ func UpdateLinkHandler(c *gin.Context) {
 		ErrorResponse(c, http.StatusBadRequest, "Invalid input")
 		return
 	}
+	before := link
 
 	// Mise à jour des champs modifiables
-	if input.URL != nil {
+	if input.URL != nil && *input.URL != link.URL {
+		if existing, found := duplicateOf(libraryOf(link), *input.URL, link.ID); found {
+			duplicateResponse(c, existing)
+			return
+		}
 		link.URL = *input.URL
+		// L'URL canonique était celle de l'ancienne page
+		link.CanonicalURL = ""
 	}
 	if input.Title != nil {
 		link.Title = *input.Title
@@ -197,38 +291,71 @@ func UpdateLinkHandler(c *gin.Context) {
 		tagsJSON, _ := json.Marshal(*input.Tags)
 		link.Tags = tagsJSON
 	}
+	if input.Notes != nil {
+		link.Notes = *input.Notes
+	}
+	if input.Read != nil {
+		if !*input.Read {
+			link.ReadAt = nil
+		} else if link.ReadAt == nil {
+			now := time.Now()
+			link.ReadAt = &now
+		}
+	}
+	if input.Archived != nil {
+		if !*input.Archived {
+			link.ArchivedAt = nil
+		} else if link.ArchivedAt == nil {
+			now := time.Now()
+			link.ArchivedAt = &now
+		}
+	}
+	if input.CollectionID != nil {
+		// Seul le propriétaire range ses liens personnels, y compris pour les sortir d'une collection
+		personal := link.WorkspaceID == nil && link.UserID == user.ID
+		if personal && *input.CollectionID == 0 {
+			link.CollectionID = nil
+		} else if personal && ownsCollection(user.ID, *input.CollectionID) {
+			link.CollectionID = input.CollectionID
+		} else {
+			ErrorResponse(c, http.StatusBadRequest, "Collection not found")
+			return
+		}
+	}
 
-	if err := db.DB.Save(&link).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "Could not update the link")
+	// Chaque modification de l'URL, du titre ou des tags garde une version du lien
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&link).Error; err != nil {
+			return err
+		}
+		return saveRevision(tx, &before, link, user, nil)
+	}); err != nil {
+		saveFailed(c, link, "Could not update the link")
 		return
 	}
+	recordLinkChange(c, user, "link.update", &before, &link)
 
 	SuccessResponse(c, http.StatusOK, gin.H{
-		"id":    link.ID,
-		"url":   link.URL,
-		"title": link.Title,
-		"tags":  link.Tags,
+		"id":            link.ID,
+		"url":           link.URL,
+		"title":         link.Title,
+		"tags":          link.Tags,
+		"notes":         link.Notes,
+		"read_at":       link.ReadAt,
+		"archived_at":   link.ArchivedAt,
+		"collection_id": link.CollectionID,
+		"workspace_id":  link.WorkspaceID,
 	})
 }
 
 func DeleteLinkHandler(c *gin.Context) {
-	claims, err := auth.ValidateToken(c)
-	if err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	email := claims.Issuer
-	var user models.User
-	if err := db.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	linkID := c.Param("id")
-	var link models.Link
-	if err := db.DB.Where("id = ? AND user_id = ?", linkID, user.ID).First(&link).Error; err != nil {
-		ErrorResponse(c, http.StatusNotFound, "Link not found")
+	link, ok := linkForRequest(c, user, accessEdit)
+	if !ok {
 		return
 	}
 
@@ -236,30 +363,47 @@ func DeleteLinkHandler(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, "Could not delete link")
 		return
 	}
+	recordLinkChange(c, user, "link.delete", &link, nil)
 
 	SuccessResponse(c, http.StatusOK, gin.H{"message": "Link deleted successfully"})
 }
 
-func GetLinkHandler(c *gin.Context) {
-	claims, err := auth.ValidateToken(c)
-	if err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
+// purgeLinks permanently deletes the links with their comments, shares and grants, inside tx
+func purgeLinks(tx *gorm.DB, linkIDs []uint) error {
+	if len(linkIDs) == 0 {
+		return nil
 	}
 
+	comments := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("link_id IN ?", linkIDs)
+	if err := tx.Where("comment_id IN (?)", comments).Delete(&models.CommentMention{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.Comment{},
+		&models.Share{},
+		&models.Grant{},
+		&models.LinkRevision{},
+	} {
+		if err := tx.Unscoped().Where("link_id IN ?", linkIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Where("id IN ?", linkIDs).Delete(&models.Link{}).Error
+}
+
+func GetLinkHandler(c *gin.Context) {
 	// On récupère l'utilisateur
-	var user models.User
-	if err := db.DB.Where("email = ?", claims.Issuer).First(&user).Error; err != nil {
-		ErrorResponse(c, http.StatusUnauthorized, "User not found")
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	// On récupère l'ID du lien depuis l'URL
-	linkID := c.Param("id")
-	var link models.Link
-	if err := db.DB.Where("id = ? AND user_id = ?", linkID, user.ID).First(&link).Error; err != nil {
-		ErrorResponse(c, http.StatusNotFound, "Link not found")
+	// On récupère le lien depuis l'URL, personnel ou d'un workspace dont l'utilisateur est membre
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
 		return
 	}
-	SuccessResponse(c, http.StatusOK, link)
+	links := []models.Link{link}
+	withCommentCounts(links)
+	SuccessResponse(c, http.StatusOK, links[0])
 }
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonRequest builds a request with an optional JSON body
+func jsonRequest(method, path string, payload interface{}) *http.Request {
+	var body io.Reader
+	if payload != nil {
+		raw, _ := json.Marshal(payload)
+		body = bytes.NewBuffer(raw)
+	}
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func jsonDecode(resp *httptest.ResponseRecorder, v interface{}) error {
+	return json.Unmarshal(resp.Body.Bytes(), v)
+}
+
+func postJSON(router *gin.Engine, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	return sendJSON(router, "POST", path, token, payload)
+}
+
+func sendJSON(router *gin.Engine, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	req := jsonRequest(method, path, payload)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// uploadFile sends content as the "file" field of a multipart form
+func uploadFile(router *gin.Engine, path, token, filename, content string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	form.Close()
+
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (r *recordingMailer) Send(msg mailer.Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+// tokenFromMail extrait le paramètre token du lien envoyé par email
+func tokenFromMail(t *testing.T, msg mailer.Message) string {
+	start := strings.Index(msg.Body, "http")
+	assert.GreaterOrEqual(t, start, 0)
+	link := strings.Fields(msg.Body[start:])[0]
+	u, err := url.Parse(link)
+	assert.NoError(t, err)
+	return u.Query().Get("token")
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/importer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Ce que deviennent les dossiers des favoris importés (paramètre folders)
+const (
+	FoldersAsTags        = "tags"
+	FoldersAsCollections = "collections"
+	FoldersIgnored       = "ignore"
+)
+
+// ImportFailure is a bookmark that could not be imported
+type ImportFailure struct {
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport sums up an import. Skipped bookmarks are already saved links.
+type ImportReport struct {
+	Created  int             `json:"created"`
+	Skipped  int             `json:"skipped"`
+	Failed   int             `json:"failed"`
+	Failures []ImportFailure `json:"failures"`
+}
+
+func (r *ImportReport) fail(bookmark importer.Bookmark, reason string) {
+	r.Failed++
+	r.Failures = append(r.Failures, ImportFailure{URL: bookmark.URL, Title: bookmark.Title, Reason: reason})
+}
+
+// importFile opens the uploaded file (multipart field "file"), or the request body
+func importFile(c *gin.Context) (io.ReadCloser, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(config.Int("IMPORT_MAX_SIZE_MB", 10))<<20)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "A file is required")
+			return nil, false
+		}
+		file, err := header.Open()
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Could not read the file")
+			return nil, false
+		}
+		return file, true
+	}
+	return c.Request.Body, true
+}
+
+// validBookmarkURL accepts absolute http and https URLs only
+func validBookmarkURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// bookmarkTags merges the tags of the bookmark with its folders, without duplicates
+func bookmarkTags(bookmark importer.Bookmark, folders string) []string {
+	candidates := bookmark.Tags
+	if folders == FoldersAsTags {
+		candidates = append(append([]string(nil), bookmark.Folders...), bookmark.Tags...)
+	}
+
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range candidates {
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// collectionNamed finds the collection of the user with this name, or creates it
+func collectionNamed(tx *gorm.DB, userID uint, name string) (uint, error) {
+	var collection models.Collection
+	err := tx.Where("user_id = ? AND name = ?", userID, name).First(&collection).Error
+	if err == gorm.ErrRecordNotFound {
+		collection = models.Collection{Name: name, UserID: userID}
+		err = tx.Create(&collection).Error
+	}
+	return collection.ID, err
+}
+
+// importFolders reads the folders parameter, collections are only possible for personal links
+func importFolders(c *gin.Context, workspaceID *uint) (string, bool) {
+	folders := c.DefaultQuery("folders", FoldersAsTags)
+	switch folders {
+	case FoldersAsTags, FoldersIgnored:
+	case FoldersAsCollections:
+		if workspaceID != nil {
+			ErrorResponse(c, http.StatusBadRequest, "Collections only group personal links")
+			return "", false
+		}
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "folders must be tags, collections or ignore")
+		return "", false
+	}
+	return folders, true
+}
+
+// importBookmarks saves the bookmarks as links of the user, or of the workspace. Links whose
+// URL is already saved in the same scope are skipped, metadata of new links is fetched
+// in the background. progress, if set, is called after each bookmark.
+func importBookmarks(user models.User, scope *gorm.DB, workspaceID *uint, bookmarks []importer.Bookmark, folders, ip string, progress func(processed int, report ImportReport)) ImportReport {
+	report := ImportReport{Failures: []ImportFailure{}}
+
+	saved, err := savedURLKeys(scope)
+	if err != nil {
+		saved = map[string]bool{}
+	}
+
+	// Un import en arrière-plan peut attendre la file, pas une requête
+	scrape := enqueueScrape
+	if progress != nil {
+		scrape = waitScrape
+	}
+
+	collections := map[string]uint{}
+	for i, bookmark := range bookmarks {
+		if progress != nil && i > 0 {
+			progress(i, report)
+		}
+		if !validBookmarkURL(bookmark.URL) {
+			report.fail(bookmark, "Unsupported URL")
+			continue
+		}
+		if saved[urlnorm.Normalize(bookmark.URL)] {
+			report.Skipped++
+			continue
+		}
+
+		tagsJSON, _ := json.Marshal(bookmarkTags(bookmark, folders))
+		link := models.Link{
+			URL:         bookmark.URL,
+			Title:       bookmark.Title,
+			Tags:        tagsJSON,
+			Description: bookmark.Description,
+			Notes:       bookmark.Notes,
+			UserID:      user.ID,
+			WorkspaceID: workspaceID,
+		}
+		if link.Title == "" {
+			link.Title = bookmark.URL
+		}
+		if !bookmark.AddedAt.IsZero() {
+			link.CreatedAt = bookmark.AddedAt
+		}
+		// Les exports ne donnent pas la date de lecture, on prend la date d'ajout
+		if bookmark.Read {
+			readAt := link.CreatedAt
+			if readAt.IsZero() {
+				readAt = time.Now()
+			}
+			link.ReadAt = &readAt
+		}
+
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			// Le dossier le plus profond donne la collection
+			if folders == FoldersAsCollections && len(bookmark.Folders) > 0 {
+				name := bookmark.Folders[len(bookmark.Folders)-1]
+				if _, ok := collections[name]; !ok {
+					id, err := collectionNamed(tx, user.ID, name)
+					if err != nil {
+						return err
+					}
+					collections[name] = id
+				}
+				id := collections[name]
+				link.CollectionID = &id
+			}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+			return saveRevision(tx, nil, link, user, nil)
+		})
+		if err != nil {
+			report.fail(bookmark, "Could not save the link")
+			continue
+		}
+
+		saved[link.NormalizedURL] = true
+		report.Created++
+		scrape(link.ID, link.URL)
+	}
+
+	if progress != nil {
+		progress(len(bookmarks), report)
+	}
+
+	event := models.AuditEvent{ActorID: &user.ID, Action: "link.import", TargetType: "link", IP: ip, WorkspaceID: workspaceID}
+	if workspaceID == nil {
+		event.OwnerID = &user.ID
+	}
+	audit.Change(event, nil, gin.H{"created": report.Created, "skipped": report.Skipped, "failed": report.Failed})
+	return report
+}
+
+// ImportNetscapeHandler imports a bookmarks.html export of a browser, sent as the "file" field of a
+// multipart form or as the request body. folders=tags (default), collections or ignore tells
+// what the bookmark folders become.
+func ImportNetscapeHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	scope, workspaceID, ok := linkScope(c, user, accessEdit)
+	if !ok {
+		return
+	}
+
+	folders, ok := importFolders(c, workspaceID)
+	if !ok {
+		return
+	}
+
+	file, ok := importFile(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	bookmarks, err := importer.ParseNetscape(file)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Could not read the bookmarks file")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, importBookmarks(user, scope, workspaceID, bookmarks, folders, c.ClientIP(), nil))
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const bookmarksFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3 PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><H3>Dev</H3>
+        <DL><p>
+            <DT><A HREF="https://go.dev/" ADD_DATE="1600000000" TAGS="lang">Go</A>
+            <DT><A HREF="https://ziglang.org/">Zig</A>
+            <DT><A HREF="https://ziglang.org/">Zig again</A>
+        </DL><p>
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        <DT><A HREF="https://existing.example.com/">Existing</A>
+    </DL><p>
+</DL>
+`
+
+func importRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.POST("/import/netscape", middleware.AuthRequired(), ImportNetscapeHandler)
+	r.POST("/import/:format", middleware.AuthRequired(), ImportHandler)
+	r.GET("/import/jobs/:id", middleware.AuthRequired(), GetImportJobHandler)
+	return r
+}
+
+func TestImportNetscape(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com")
+	assert.NoError(t, db.DB.Create(&models.Link{URL: "https://existing.example.com/", Title: "Existing", Tags: []byte(`[]`), UserID: users[0].ID}).Error)
+	router := importRouter()
+
+	resp := uploadFile(router, "/import/netscape", tokens[0], "bookmarks.html", bookmarksFile)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var report ResponseData[ImportReport]
+	assert.NoError(t, jsonDecode(resp, &report))
+	assert.Equal(t, 2, report.Data.Created)
+	assert.Equal(t, 2, report.Data.Skipped)
+	assert.Equal(t, 1, report.Data.Failed)
+	assert.Equal(t, "javascript:alert(1)", report.Data.Failures[0].URL)
+
+	// Les dossiers deviennent des tags, la date d'ajout est conservée
+	var link models.Link
+	assert.NoError(t, db.DB.Where("url = ?", "https://go.dev/").First(&link).Error)
+	assert.JSONEq(t, `["Dev", "lang"]`, string(link.Tags))
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), link.CreatedAt.UTC())
+
+	// Importer à nouveau ne crée pas de doublon
+	assert.NoError(t, jsonDecode(uploadFile(router, "/import/netscape", tokens[0], "bookmarks.html", bookmarksFile), &report))
+	assert.Equal(t, 0, report.Data.Created)
+	assert.Equal(t, 4, report.Data.Skipped)
+}
+
+func TestImportNetscapeFoldersAsCollections(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com")
+	router := importRouter()
+
+	assert.Equal(t, http.StatusBadRequest, uploadFile(router, "/import/netscape?folders=folders", tokens[0], "bookmarks.html", bookmarksFile).Code)
+	assert.Equal(t, http.StatusOK, uploadFile(router, "/import/netscape?folders=collections", tokens[0], "bookmarks.html", bookmarksFile).Code)
+
+	var collection models.Collection
+	assert.NoError(t, db.DB.Where("user_id = ? AND name = ?", users[0].ID, "Dev").First(&collection).Error)
+	var link models.Link
+	assert.NoError(t, db.DB.Where("url = ?", "https://ziglang.org/").First(&link).Error)
+	assert.Equal(t, collection.ID, *link.CollectionID)
+	assert.JSONEq(t, `[]`, string(link.Tags))
+}
+
+func TestImportJob(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "other@example.com")
+	router := importRouter()
+
+	pinboard := `[
+		{"href":"https://go.dev/","description":"Go","extended":"Read the tour","tags":"go","time":"2020-09-13T12:26:40Z","toread":"no"},
+		{"href":"https://ziglang.org/","description":"Zig","extended":"","tags":"","time":"2020-09-13T12:28:20Z","toread":"yes"}
+	]`
+	assert.Equal(t, http.StatusNotFound, uploadFile(router, "/import/delicious", tokens[0], "export.json", pinboard).Code)
+	assert.Equal(t, http.StatusBadRequest, uploadFile(router, "/import/pinboard", tokens[0], "export.json", "not json").Code)
+
+	resp := uploadFile(router, "/import/pinboard", tokens[0], "export.json", pinboard)
+	assert.Equal(t, http.StatusAccepted, resp.Code, resp.Body.String())
+	var job ResponseData[models.ImportJob]
+	assert.NoError(t, jsonDecode(resp, &job))
+	assert.Equal(t, 2, job.Data.Total)
+
+	// On interroge le job jusqu'à la fin de l'import
+	path := fmt.Sprintf("/import/jobs/%d", job.Data.ID)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, tokens[1], nil).Code)
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, jsonDecode(sendJSON(router, "GET", path, tokens[0], nil), &job))
+		return job.Data.Status == models.ImportDone
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 2, job.Data.Processed)
+	assert.Equal(t, 2, job.Data.Created)
+
+	var links []models.Link
+	assert.NoError(t, db.DB.Where("user_id = ?", users[0].ID).Order("created_at").Find(&links).Error)
+	assert.Len(t, links, 2)
+	assert.Equal(t, "Read the tour", links[0].Notes)
+	assert.NotNil(t, links[0].ReadAt)
+	assert.Nil(t, links[1].ReadAt)
+}
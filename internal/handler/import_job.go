@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/importer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Exports des autres services, importés en arrière-plan
+var bookmarkParsers = map[string]func(io.Reader) ([]importer.Bookmark, error){
+	"pocket":   importer.ParsePocket,
+	"pinboard": importer.ParsePinboard,
+	"raindrop": importer.ParseRaindrop,
+	"linkding": importer.ParseLinkding,
+}
+
+// La progression est enregistrée tous les importProgressStep favoris
+const importProgressStep = 25
+
+// runImport imports the bookmarks and keeps the progress of the job up to date
+func runImport(job models.ImportJob, user models.User, scope *gorm.DB, bookmarks []importer.Bookmark, folders, ip string) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorLogger.Println("Import job failed:", r)
+			db.DB.Model(&job).Updates(map[string]interface{}{"status": models.ImportFailed, "error": fmt.Sprint(r)})
+		}
+	}()
+
+	report := importBookmarks(user, scope, job.WorkspaceID, bookmarks, folders, ip, func(processed int, report ImportReport) {
+		if processed%importProgressStep == 0 {
+			db.DB.Model(&job).Updates(map[string]interface{}{
+				"processed": processed,
+				"created":   report.Created,
+				"skipped":   report.Skipped,
+				"failed":    report.Failed,
+			})
+		}
+	})
+
+	failures, _ := json.Marshal(report.Failures)
+	db.DB.Model(&job).Updates(map[string]interface{}{
+		"status":    models.ImportDone,
+		"processed": len(bookmarks),
+		"created":   report.Created,
+		"skipped":   report.Skipped,
+		"failed":    report.Failed,
+		"failures":  failures,
+	})
+}
+
+// ImportHandler starts the import of the export of another service: pocket (HTML or CSV),
+// pinboard (JSON), raindrop (CSV) or linkding (JSON). The file is read right away, the links
+// are saved in the background and the returned job can be polled for progress.
+func ImportHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	parse, ok := bookmarkParsers[c.Param("format")]
+	if !ok {
+		ErrorResponse(c, http.StatusNotFound, "Unknown import format")
+		return
+	}
+
+	scope, workspaceID, ok := linkScope(c, user, accessEdit)
+	if !ok {
+		return
+	}
+
+	folders, ok := importFolders(c, workspaceID)
+	if !ok {
+		return
+	}
+
+	file, ok := importFile(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	bookmarks, err := parse(file)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Could not read the export: "+err.Error())
+		return
+	}
+
+	job := models.ImportJob{
+		UserID:      user.ID,
+		WorkspaceID: workspaceID,
+		Format:      c.Param("format"),
+		Status:      models.ImportRunning,
+		Total:       len(bookmarks),
+	}
+	if err := db.DB.Create(&job).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not start the import")
+		return
+	}
+	go runImport(job, user, scope, bookmarks, folders, c.ClientIP())
+
+	SuccessResponse(c, http.StatusAccepted, job)
+}
+
+// GetImportJobsHandler lists the recent imports of the user
+func GetImportJobsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	jobs := []models.ImportJob{}
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id DESC").Limit(50).Find(&jobs).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch imports")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, jobs)
+}
+
+// GetImportJobHandler returns the progress of an import, and its failures once done
+func GetImportJobHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var job models.ImportJob
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&job).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Import not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, job)
+}
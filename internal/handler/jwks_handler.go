@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys validating our tokens (RFC 7517)
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.JWKS())
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// likePattern builds a LIKE pattern matching the text anywhere, its wildcards are escaped
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(text))
+	return "%" + escaped + "%"
+}
+
+// filterLinks applies the filters of the link list to the query:
+//   - tag: links having the tag, repeatable to require several tags
+//   - q: text searched in the URL, title, description and notes
+//   - collection_id: links of a collection, 0 for links without collection
+//   - read: true or false
+//   - archived: false by default, true for the archived links only, all for both
+//   - from, to: RFC 3339 bounds of the creation date
+//
+// Links are sorted by creation date.
+func filterLinks(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	for _, tag := range c.QueryArray("tag") {
+		query = query.Where(datatypes.JSONArrayQuery("tags").Contains(tag))
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := likePattern(q)
+		query = query.Where(
+			`LOWER(url) LIKE ? ESCAPE '\' OR LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\' OR LOWER(notes) LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern, pattern,
+		)
+	}
+
+	switch collectionID := c.Query("collection_id"); collectionID {
+	case "":
+	case "0":
+		query = query.Where("collection_id IS NULL")
+	default:
+		query = query.Where("collection_id = ?", collectionID)
+	}
+
+	switch c.Query("read") {
+	case "":
+	case "true":
+		query = query.Where("read_at IS NOT NULL")
+	case "false":
+		query = query.Where("read_at IS NULL")
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "read must be true or false")
+		return nil, false
+	}
+
+	switch c.Query("archived") {
+	case "", "false":
+		query = query.Where("archived_at IS NULL")
+	case "true":
+		query = query.Where("archived_at IS NOT NULL")
+	case "all":
+	default:
+		ErrorResponse(c, http.StatusBadRequest, "archived must be true, false or all")
+		return nil, false
+	}
+
+	for param, condition := range map[string]string{
+		"from": "created_at >= ?",
+		"to":   "created_at <= ?",
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, errInvalidTime.Error())
+			return nil, false
+		}
+		query = query.Where(condition, at)
+	}
+
+	return query.Order("created_at, id"), true
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/oidc"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	oidcProvider *oidc.Provider
+
+	errNoAccount = errors.New("no account linked to this identity")
+	// Le compte a pu être créé par un tiers avec l'adresse de la victime
+	errUnverifiedAccount = errors.New("the account with this email is not verified")
+)
+
+// UseOIDC enables the OpenID Connect login with the given provider
+func UseOIDC(p *oidc.Provider) {
+	oidcProvider = p
+}
+
+// OIDCLoginHandler redirects the browser to the identity provider
+func OIDCLoginHandler(c *gin.Context) {
+	if oidcProvider == nil {
+		ErrorResponse(c, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+
+	state, err1 := auth.RandomToken(32)
+	verifier, err2 := auth.RandomToken(48)
+	nonce, err3 := auth.RandomToken(32)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not start OIDC login")
+		return
+	}
+
+	loginState := models.OIDCLoginState{
+		StateHash: auth.HashToken(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(oidcStateTTL),
+
+		CookieSession: cookieSessionRequested(c),
+	}
+	if err := db.DB.Create(&loginState).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not start OIDC login")
+		return
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.PKCEChallenge(verifier))
+	if err != nil {
+		logger.ErrorLogger.Println("OIDC discovery failed:", err)
+		ErrorResponse(c, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallbackHandler exchanges the code and issues the same token as LoginUserHandler
+func OIDCCallbackHandler(c *gin.Context) {
+	if oidcProvider == nil {
+		ErrorResponse(c, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		ErrorResponse(c, http.StatusUnauthorized, "Identity provider error: "+errParam)
+		return
+	}
+
+	// Le state n'est utilisable qu'une fois
+	var loginState models.OIDCLoginState
+	if err := db.DB.Where("state_hash = ?", auth.HashToken(c.Query("state"))).First(&loginState).Error; err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired state")
+		return
+	}
+	db.DB.Unscoped().Delete(&loginState)
+	if time.Now().After(loginState.ExpiresAt) {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired state")
+		return
+	}
+
+	identity, err := oidcProvider.Exchange(c.Request.Context(), c.Query("code"), loginState.Verifier, loginState.Nonce)
+	if err != nil {
+		logger.ErrorLogger.Println("OIDC exchange failed:", err)
+		ErrorResponse(c, http.StatusUnauthorized, "Could not authenticate with the identity provider")
+		return
+	}
+
+	user, err := userForIdentity(identity)
+	if err == errNoAccount {
+		ErrorResponse(c, http.StatusForbidden, "No account linked to this identity")
+		return
+	}
+	if err == errUnverifiedAccount {
+		ErrorResponse(c, http.StatusForbidden, "Verify the email of your account before signing in with this provider")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not link identity")
+		return
+	}
+
+	completeLogin(c, user, loginState.CookieSession)
+}
+
+// userForIdentity finds the user linked to the identity. An unknown identity is linked to the
+// account with the same verified email, or to a new account when OIDC_AUTO_PROVISION is enabled.
+func userForIdentity(identity *oidc.Identity) (models.User, error) {
+	var user models.User
+
+	var link models.ExternalIdentity
+	err := db.DB.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+	if err == nil {
+		err = db.DB.First(&user, link.UserID).Error
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// Sans email vérifié par le fournisseur, on ne peut rien rattacher
+	if identity.Email == "" || !identity.EmailVerified {
+		return user, errNoAccount
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("lower(email) = lower(?)", identity.Email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !config.Bool("OIDC_AUTO_PROVISION", false) {
+				return errNoAccount
+			}
+			user, err = provisionUser(tx, identity.Email)
+		}
+		if err != nil {
+			return err
+		}
+		// Sinon celui qui a créé le compte, et connaît son mot de passe, récupérerait l'identité
+		if !user.IsVerified() {
+			return errUnverifiedAccount
+		}
+
+		return tx.Create(&models.ExternalIdentity{
+			UserID:  user.ID,
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+			Email:   identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return user, err
+	}
+
+	audit.Record(models.AuditEvent{ActorID: &user.ID, Action: "auth.identity_linked", TargetType: "oidc", TargetID: identity.Subject}, gin.H{"issuer": identity.Issuer})
+	return user, nil
+}
+
+// provisionUser creates a verified account with an unusable random password
+func provisionUser(tx *gorm.DB, email string) (models.User, error) {
+	random, err := auth.RandomToken(32)
+	if err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := auth.HashPassword(random)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	user := models.User{Email: email, Password: hashedPassword, EmailVerifiedAt: &now}
+	return user, tx.Create(&user).Error
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/oidc"
+	"github.com/DebroyeAntoine/go_link_vault/internal/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// oidcLogin runs the whole flow against the mock provider and returns the callback response
+func oidcLogin(t *testing.T, router *gin.Engine) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusFound, resp.Code)
+
+	// Le fournisseur approuve et redirige vers notre callback
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	idpResp, err := client.Get(resp.Header().Get("Location"))
+	assert.NoError(t, err)
+	callback, _ := url.Parse(idpResp.Header.Get("Location"))
+
+	req, _ = http.NewRequest("GET", "/auth/oidc/callback?"+callback.RawQuery, nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func setupOIDC(t *testing.T) (*oidctest.Provider, *gin.Engine) {
+	idp := oidctest.NewProvider("vault", "secret")
+	UseOIDC(oidc.NewProvider(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "vault",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	}))
+	t.Cleanup(func() {
+		UseOIDC(nil)
+		idp.Close()
+	})
+
+	router := gin.Default()
+	router.GET("/auth/oidc/login", OIDCLoginHandler)
+	router.GET("/auth/oidc/callback", OIDCCallbackHandler)
+	return idp, router
+}
+
+func TestOIDCLoginLinksExistingAccount(t *testing.T) {
+	db.SetupTestDB()
+	idp, router := setupOIDC(t)
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: idp.Email, Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+
+	// Un compte dont l'adresse n'est pas vérifiée n'est pas rattaché
+	assert.Equal(t, http.StatusForbidden, oidcLogin(t, router).Code)
+	var count int64
+	db.DB.Model(&models.ExternalIdentity{}).Count(&count)
+	assert.Zero(t, count)
+
+	now := time.Now()
+	assert.NoError(t, db.DB.Model(&user).Update("email_verified_at", &now).Error)
+	resp := oidcLogin(t, router)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body map[string]string
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.NotEmpty(t, body["token"])
+
+	var identity models.ExternalIdentity
+	assert.NoError(t, db.DB.Where("subject = ?", idp.Subject).First(&identity).Error)
+	assert.Equal(t, user.ID, identity.UserID)
+
+	// Une fois liée, l'identité est retrouvée même si l'email change chez le fournisseur
+	idp.Email = "renamed@example.com"
+	assert.Equal(t, http.StatusOK, oidcLogin(t, router).Code)
+}
+
+func TestOIDCLoginWithoutAccount(t *testing.T) {
+	db.SetupTestDB()
+	idp, router := setupOIDC(t)
+
+	t.Setenv("OIDC_AUTO_PROVISION", "false")
+	assert.Equal(t, http.StatusForbidden, oidcLogin(t, router).Code)
+
+	t.Setenv("OIDC_AUTO_PROVISION", "true")
+	assert.Equal(t, http.StatusOK, oidcLogin(t, router).Code)
+
+	var user models.User
+	assert.NoError(t, db.DB.Where("email = ?", idp.Email).First(&user).Error)
+	assert.True(t, user.IsVerified())
+}
+
+func TestOIDCCallbackRejectsUnknownState(t *testing.T) {
+	db.SetupTestDB()
+	_, router := setupOIDC(t)
+
+	req, _ := http.NewRequest("GET", "/auth/oidc/callback?code=abc&state=forged", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
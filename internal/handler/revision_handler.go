@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RevisionView is a version of a link. Current is set on the latest one.
+type RevisionView struct {
+	ID             uint           `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	EditorID       *uint          `json:"editor_id,omitempty"`
+	EditorEmail    string         `json:"editor_email,omitempty"`
+	URL            string         `json:"url"`
+	Title          string         `json:"title"`
+	Tags           datatypes.JSON `json:"tags"`
+	RestoredFromID *uint          `json:"restored_from_id,omitempty"`
+	Current        bool           `json:"current"`
+}
+
+// revisionFields holds the fields of a link kept by its revisions
+func revisionFields(url, title string, tags datatypes.JSON) gin.H {
+	return gin.H{"url": url, "title": title, "tags": tags}
+}
+
+// saveRevision records the new state of the link when its URL, title or tags changed.
+// before is nil for a creation. A link created before the history gets its previous
+// state recorded first, so it can be restored.
+func saveRevision(tx *gorm.DB, before *models.Link, link models.Link, editor models.User, restoredFrom *uint) error {
+	if before != nil {
+		_, changes := audit.Diff(
+			revisionFields(before.URL, before.Title, before.Tags),
+			revisionFields(link.URL, link.Title, link.Tags),
+		)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		var count int64
+		if err := tx.Model(&models.LinkRevision{}).Where("link_id = ?", link.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			original := models.LinkRevision{LinkID: link.ID, URL: before.URL, Title: before.Title, Tags: before.Tags}
+			if err := tx.Create(&original).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Create(&models.LinkRevision{
+		LinkID:         link.ID,
+		EditorID:       &editor.ID,
+		URL:            link.URL,
+		Title:          link.Title,
+		Tags:           link.Tags,
+		RestoredFromID: restoredFrom,
+	}).Error
+}
+
+// linkRevisions returns the revisions of a link, most recent first
+func linkRevisions(linkID uint) ([]RevisionView, error) {
+	revisions := []RevisionView{}
+	err := db.DB.Table("link_revisions").
+		Select("link_revisions.id, link_revisions.created_at, link_revisions.editor_id, users.email AS editor_email, "+
+			"link_revisions.url, link_revisions.title, link_revisions.tags, link_revisions.restored_from_id").
+		Joins("LEFT JOIN users ON users.id = link_revisions.editor_id").
+		Where("link_revisions.link_id = ?", linkID).
+		Order("link_revisions.id DESC").
+		Scan(&revisions).Error
+	if len(revisions) > 0 {
+		revisions[0].Current = true
+	}
+	return revisions, err
+}
+
+// findRevision returns the revision with the given id among the revisions of a link
+func findRevision(revisions []RevisionView, id string) (RevisionView, bool) {
+	for _, revision := range revisions {
+		if id == strconv.FormatUint(uint64(revision.ID), 10) {
+			return revision, true
+		}
+	}
+	return RevisionView{}, false
+}
+
+// GetRevisionsHandler lists the revisions of a link, to anyone who can see it
+func GetRevisionsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	revisions, err := linkRevisions(link.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch revisions")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, revisions)
+}
+
+// DiffRevisionsHandler compares the revisions from and to of a link. to defaults to the current revision.
+func DiffRevisionsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	revisions, err := linkRevisions(link.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch revisions")
+		return
+	}
+
+	from, ok := findRevision(revisions, c.Query("from"))
+	if !ok {
+		ErrorResponse(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+	to, ok := findRevision(revisions, c.DefaultQuery("to", strconv.FormatUint(uint64(revisions[0].ID), 10)))
+	if !ok {
+		ErrorResponse(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+
+	before, after := audit.Diff(
+		revisionFields(from.URL, from.Title, from.Tags),
+		revisionFields(to.URL, to.Title, to.Tags),
+	)
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"from":   from,
+		"to":     to,
+		"before": before,
+		"after":  after,
+	})
+}
+
+// RestoreRevisionHandler gives back to a link the URL, title and tags of one of its revisions.
+// The restoration is itself a new revision.
+func RestoreRevisionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := linkForRequest(c, user, accessEdit)
+	if !ok {
+		return
+	}
+
+	var revision models.LinkRevision
+	if err := db.DB.Where("id = ? AND link_id = ?", c.Param("revision_id"), link.ID).First(&revision).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+
+	before := link
+	if revision.URL != link.URL {
+		if existing, found := duplicateOf(libraryOf(link), revision.URL, link.ID); found {
+			duplicateResponse(c, existing)
+			return
+		}
+		link.URL = revision.URL
+		link.CanonicalURL = ""
+	}
+	link.Title = revision.Title
+	link.Tags = revision.Tags
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&link).Error; err != nil {
+			return err
+		}
+		return saveRevision(tx, &before, link, user, &revision.ID)
+	}); err != nil {
+		saveFailed(c, link, "Could not restore the revision")
+		return
+	}
+	recordLinkChange(c, user, "link.restore", &before, &link)
+
+	counted := []models.Link{link}
+	withCommentCounts(counted)
+	SuccessResponse(c, http.StatusOK, counted[0])
+}
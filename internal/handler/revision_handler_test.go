@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func revisionRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.GET("/link/:id/revisions", middleware.AuthRequired(), GetRevisionsHandler)
+	r.GET("/link/:id/revisions/diff", middleware.AuthRequired(), DiffRevisionsHandler)
+	r.POST("/link/:id/revisions/:revision_id/restore", middleware.AuthRequired(), RestoreRevisionHandler)
+	return r
+}
+
+func TestLinkRevisions(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "reader@example.com")
+	owner, reader := tokens[0], tokens[1]
+
+	// Un lien créé avant l'historique n'a pas de version
+	link := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`["go"]`), UserID: users[0].ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: users[0].ID, UserID: users[1].ID, LinkID: &link.ID, Permission: models.PermissionView}).Error)
+
+	router := revisionRouter()
+	path := fmt.Sprintf("/link/%d", link.ID)
+
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, owner, map[string]interface{}{"title": "The Go language", "tags": []string{"go", "lang"}}).Code)
+	// Changer seulement la collection ne crée pas de version
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, owner, map[string]interface{}{"collection_id": 0}).Code)
+
+	var revisions ResponseData[[]RevisionView]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path+"/revisions", reader, nil), &revisions))
+	assert.Len(t, revisions.Data, 2)
+	current, original := revisions.Data[0], revisions.Data[1]
+	assert.True(t, current.Current)
+	assert.Equal(t, "owner@example.com", current.EditorEmail)
+	assert.Equal(t, "Go", original.Title)
+	assert.Nil(t, original.EditorID)
+
+	var diff ResponseData[struct {
+		Before map[string]interface{} `json:"before"`
+		After  map[string]interface{} `json:"after"`
+	}]
+	resp := sendJSON(router, "GET", fmt.Sprintf("%s/revisions/diff?from=%d", path, original.ID), reader, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, jsonDecode(resp, &diff))
+	assert.Equal(t, map[string]interface{}{"title": "Go", "tags": []interface{}{"go"}}, diff.Data.Before)
+	assert.Equal(t, map[string]interface{}{"title": "The Go language", "tags": []interface{}{"go", "lang"}}, diff.Data.After)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path+"/revisions/diff?from=9999", reader, nil).Code)
+
+	// Restaurer demande le droit de modifier le lien
+	restore := fmt.Sprintf("%s/revisions/%d/restore", path, original.ID)
+	assert.Equal(t, http.StatusForbidden, postJSON(router, restore, reader, nil).Code)
+	assert.Equal(t, http.StatusOK, postJSON(router, restore, owner, nil).Code)
+
+	var restored models.Link
+	assert.NoError(t, db.DB.First(&restored, link.ID).Error)
+	assert.Equal(t, "Go", restored.Title)
+	assert.JSONEq(t, `["go"]`, string(restored.Tags))
+
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", path+"/revisions", owner, nil), &revisions))
+	assert.Len(t, revisions.Data, 3)
+	assert.Equal(t, original.ID, *revisions.Data[0].RestoredFromID)
+}
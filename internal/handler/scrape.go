@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/scraper"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+)
+
+var (
+	scrapeQueue     *scraper.Queue
+	scrapeQueueOnce sync.Once
+)
+
+// queueForScrapes starts the scrape queue on first use
+func queueForScrapes() *scraper.Queue {
+	scrapeQueueOnce.Do(func() {
+		scrapeQueue = scraper.NewQueue(
+			config.Int("SCRAPER_WORKERS", 4),
+			config.Int("SCRAPER_QUEUE_SIZE", 1000),
+			storeMetadata,
+		)
+	})
+	return scrapeQueue
+}
+
+// enqueueScrape fetches the metadata of a link in the background, without making the request
+// wait: when the queue is full the link is left without metadata, until a scrape refresh
+func enqueueScrape(linkID uint, url string) {
+	if !queueForScrapes().TryEnqueue(scraper.Job{LinkID: linkID, URL: url}) {
+		logger.ErrorLogger.Printf("Scrape queue full, metadata of link %d not fetched", linkID)
+	}
+}
+
+// waitScrape is enqueueScrape for the background jobs, it waits for room in the queue
+func waitScrape(linkID uint, url string) {
+	queueForScrapes().Enqueue(scraper.Job{LinkID: linkID, URL: url})
+}
+
+// storeMetadata saves the result of a scrape on the link
+func storeMetadata(job scraper.Job, metadata *scraper.Metadata, err error) {
+	if err != nil {
+		logger.ErrorLogger.Println("Failed to fetch metadata:", err)
+		return
+	}
+
+	updates := models.Link{
+		Description: metadata.Description,
+		Image:       metadata.Image,
+	}
+	if metadata.Canonical != "" {
+		updates.CanonicalURL = urlnorm.Normalize(metadata.Canonical)
+	}
+	db.DB.Model(&models.Link{}).Where("id = ?", job.LinkID).Updates(updates)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnqueueScrapeDoesNotBlock(t *testing.T) {
+	logger.InitLogger()
+
+	// File d'une place sans worker : le deuxième lien ne peut pas y entrer
+	queueForScrapes()
+	previous := scrapeQueue
+	scrapeQueue = scraper.NewQueue(0, 1, storeMetadata)
+	defer func() { scrapeQueue = previous }()
+
+	done := make(chan struct{})
+	go func() {
+		enqueueScrape(1, "https://go.dev")
+		enqueueScrape(2, "https://rust-lang.org")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "enqueueScrape blocked on a full queue")
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// cookieSessionRequested reports whether the client asked for a cookie session (?session=cookie)
+func cookieSessionRequested(c *gin.Context) bool {
+	return c.Query("session") == "cookie"
+}
+
+// issueSession returns the token in the body, or with a cookie session sets the HttpOnly
+// cookie and only returns the CSRF token the front-end must send back. It reports whether the
+// session was issued.
+func issueSession(c *gin.Context, user models.User, cookie bool) bool {
+	token, err := auth.CreateToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating JWT"})
+		return false
+	}
+
+	if !cookie {
+		c.JSON(http.StatusOK, gin.H{"token": token})
+		return true
+	}
+
+	csrf, err := middleware.SetSessionCookies(c, token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating CSRF token"})
+		return false
+	}
+	c.JSON(http.StatusOK, gin.H{"csrf_token": csrf})
+	return true
+}
+
+// LogoutHandler expires the session cookies. Bearer tokens are simply forgotten by the client.
+func LogoutHandler(c *gin.Context) {
+	middleware.ClearSessionCookies(c)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendWithCookies rejoue les cookies reçus, avec le header CSRF s'il est fourni
+func sendWithCookies(router http.Handler, method, path string, cookies []*http.Cookie, csrf string, payload interface{}) *httptest.ResponseRecorder {
+	req := jsonRequest(method, path, payload)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if csrf != "" {
+		req.Header.Set(middleware.CSRFHeader, csrf)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestCookieSession(t *testing.T) {
+	db.SetupTestDB()
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: "cookie@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+
+	router := accountRouter()
+	router.POST("/login", LoginUserHandler)
+	router.POST("/logout", LogoutHandler)
+
+	resp := postJSON(router, "/login?session=cookie", "", map[string]string{"email": "cookie@example.com", "password": "password"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body map[string]string
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.Empty(t, body["token"], "the token must not be readable by scripts")
+	assert.NotEmpty(t, body["csrf_token"])
+
+	cookies := resp.Result().Cookies()
+	session := findCookie(cookies, middleware.SessionCookie)
+	csrf := findCookie(cookies, middleware.CSRFCookie)
+	assert.NotNil(t, session)
+	assert.NotNil(t, csrf)
+	assert.True(t, session.HttpOnly)
+	assert.True(t, session.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+	assert.False(t, csrf.HttpOnly)
+	assert.Equal(t, body["csrf_token"], csrf.Value)
+
+	// Lecture sans token CSRF
+	assert.Equal(t, http.StatusOK, sendWithCookies(router, "GET", "/account", cookies, "", nil).Code)
+
+	// Écriture : le header CSRF doit correspondre au cookie
+	change := map[string]string{"current_password": "password", "new_password": "newpassword"}
+	assert.Equal(t, http.StatusForbidden, sendWithCookies(router, "PUT", "/account/password", cookies, "", change).Code)
+	assert.Equal(t, http.StatusForbidden, sendWithCookies(router, "PUT", "/account/password", cookies, "forged", change).Code)
+
+	resp = sendWithCookies(router, "PUT", "/account/password", cookies, csrf.Value, change)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var changed ResponseData[map[string]string]
+	assert.NoError(t, jsonDecode(resp, &changed))
+	assert.Empty(t, changed.Data["token"])
+	assert.NotEmpty(t, changed.Data["csrf_token"])
+
+	// Le mot de passe a changé : l'ancien cookie est révoqué, le nouveau fonctionne
+	assert.Equal(t, http.StatusUnauthorized, sendWithCookies(router, "GET", "/account", cookies, "", nil).Code)
+	cookies = resp.Result().Cookies()
+	assert.Equal(t, http.StatusOK, sendWithCookies(router, "GET", "/account", cookies, "", nil).Code)
+
+	resp = sendWithCookies(router, "POST", "/logout", cookies, "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	for _, cookie := range resp.Result().Cookies() {
+		assert.Empty(t, cookie.Value)
+		assert.Negative(t, cookie.MaxAge)
+	}
+}
+
+func TestBearerTokenNeedsNoCSRF(t *testing.T) {
+	db.SetupTestDB()
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: "bearer@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	token, _ := auth.CreateToken(user)
+
+	resp := sendJSON(accountRouter(), "PUT", "/account/password", token, map[string]string{
+		"current_password": "password",
+		"new_password":     "newpassword",
+	})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body ResponseData[map[string]string]
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.NotEmpty(t, body.Data["token"])
+	assert.Empty(t, resp.Result().Cookies())
+}
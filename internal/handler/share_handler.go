@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// SharePasswordHeader carries the password of a protected share
+const SharePasswordHeader = "X-Share-Password"
+
+// CreateShareInput targets exactly one of a link, a tag or a collection
+type CreateShareInput struct {
+	LinkID       *uint      `json:"link_id"`
+	Tag          string     `json:"tag"`
+	CollectionID *uint      `json:"collection_id"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Password     string     `json:"password" binding:"omitempty,min=6"`
+}
+
+// ShareView is a share as seen by its owner, the token is only returned at creation
+type ShareView struct {
+	ID                uint       `json:"id"`
+	Kind              string     `json:"kind"`
+	LinkID            *uint      `json:"link_id,omitempty"`
+	Tag               string     `json:"tag,omitempty"`
+	CollectionID      *uint      `json:"collection_id,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	PasswordProtected bool       `json:"password_protected"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func shareView(share models.Share) ShareView {
+	return ShareView{
+		ID:                share.ID,
+		Kind:              share.Kind,
+		LinkID:            share.LinkID,
+		Tag:               share.Tag,
+		CollectionID:      share.CollectionID,
+		ExpiresAt:         share.ExpiresAt,
+		PasswordProtected: share.PasswordHash != "",
+		CreatedAt:         share.CreatedAt,
+	}
+}
+
+// PublicLink is a link as seen through a share: no ID, owner or other private field
+type PublicLink struct {
+	URL         string         `json:"url"`
+	Title       string         `json:"title"`
+	Tags        datatypes.JSON `json:"tags"`
+	Description string         `json:"description,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+func publicLinks(links []models.Link) []PublicLink {
+	views := make([]PublicLink, 0, len(links))
+	for _, link := range links {
+		views = append(views, PublicLink{
+			URL:         link.URL,
+			Title:       link.Title,
+			Tags:        link.Tags,
+			Description: link.Description,
+			Image:       link.Image,
+			CreatedAt:   link.CreatedAt,
+		})
+	}
+	return views
+}
+
+// shareKind validates the target of the input and returns the kind of share
+func shareKind(user models.User, input CreateShareInput) (string, bool) {
+	targets := 0
+	kind := ""
+	if input.LinkID != nil {
+		targets++
+		kind = models.ShareLink
+		var link models.Link
+		if err := db.DB.First(&link, *input.LinkID).Error; err != nil || linkAccess(user, link) < accessEdit {
+			return "", false
+		}
+		// Un lien personnel n'est partagé publiquement que par son propriétaire
+		if link.WorkspaceID == nil && link.UserID != user.ID {
+			return "", false
+		}
+	}
+	if input.Tag != "" {
+		targets++
+		kind = models.ShareTag
+	}
+	if input.CollectionID != nil {
+		targets++
+		kind = models.ShareCollection
+		if !ownsCollection(user.ID, *input.CollectionID) {
+			return "", false
+		}
+	}
+	return kind, targets == 1
+}
+
+// CreateShareHandler creates a public URL to a link, a tag or a collection
+func CreateShareHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input CreateShareInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kind, ok := shareKind(user, input)
+	if !ok {
+		ErrorResponse(c, http.StatusBadRequest, "Exactly one existing link_id, tag or collection_id is required")
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		ErrorResponse(c, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create share")
+		return
+	}
+
+	share := models.Share{
+		UserID:       user.ID,
+		TokenHash:    auth.HashToken(token),
+		Kind:         kind,
+		LinkID:       input.LinkID,
+		Tag:          input.Tag,
+		CollectionID: input.CollectionID,
+		ExpiresAt:    input.ExpiresAt,
+	}
+	if input.Password != "" {
+		if share.PasswordHash, err = auth.HashPassword(input.Password); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Error hashing password")
+			return
+		}
+	}
+	if err := db.DB.Create(&share).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create share")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, gin.H{
+		"share": shareView(share),
+		"token": token,
+		"url":   config.BaseURL() + "/s/" + token,
+	})
+}
+
+func GetSharesHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var shares []models.Share
+	if err := db.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch shares")
+		return
+	}
+
+	views := make([]ShareView, 0, len(shares))
+	for _, share := range shares {
+		views = append(views, shareView(share))
+	}
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+// DeleteShareHandler revokes a share, its URL stops working immediately
+func DeleteShareHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Delete(&models.Share{})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not revoke share")
+		return
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Share revoked"})
+}
+
+// PublicShareHandler serves the links of a share without authentication.
+// Expired, revoked or unknown shares all answer 404.
+func PublicShareHandler(c *gin.Context) {
+	var share models.Share
+	if err := db.DB.Where("token_hash = ?", auth.HashToken(c.Param("token"))).First(&share).Error; err != nil || share.IsExpired() {
+		ErrorResponse(c, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	var owner models.User
+	if err := db.DB.First(&owner, share.UserID).Error; err != nil || owner.IsDisabled() {
+		ErrorResponse(c, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	if share.PasswordHash != "" {
+		// Même ralentissement que la connexion contre les essais de mots de passe
+		key := "share:" + strconv.FormatUint(uint64(share.ID), 10)
+		if wait := throttle.Blocked(key); wait > 0 {
+			tooManyAttempts(c, wait)
+			return
+		}
+		password := c.GetHeader(SharePasswordHeader)
+		if password == "" {
+			ErrorResponse(c, http.StatusUnauthorized, "Password required")
+			return
+		}
+		if !auth.CheckPasswordHash(password, share.PasswordHash) {
+			throttle.RecordFailure(key)
+			ErrorResponse(c, http.StatusUnauthorized, "Invalid password")
+			return
+		}
+	}
+
+	response := gin.H{"kind": share.Kind, "expires_at": share.ExpiresAt}
+	query := db.DB.Order("created_at DESC")
+	switch share.Kind {
+	case models.ShareLink:
+		query = query.Where("id = ?", share.LinkID)
+	case models.ShareTag:
+		query = query.Where("user_id = ? AND workspace_id IS NULL", share.UserID).
+			Where(datatypes.JSONArrayQuery("tags").Contains(share.Tag))
+		response["tag"] = share.Tag
+	case models.ShareCollection:
+		var collection models.Collection
+		if err := db.DB.First(&collection, share.CollectionID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, "Share not found")
+			return
+		}
+		query = query.Where("collection_id = ?", collection.ID)
+		response["name"] = collection.Name
+		response["description"] = collection.Description
+	}
+
+	var links []models.Link
+	if err := query.Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch links")
+		return
+	}
+	// Le partage cesse si son auteur n'a plus accès au lien (retiré du workspace...)
+	if share.Kind == models.ShareLink && (len(links) == 0 || linkAccess(owner, links[0]) < accessEdit) {
+		ErrorResponse(c, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	response["links"] = publicLinks(links)
+	SuccessResponse(c, http.StatusOK, response)
+}
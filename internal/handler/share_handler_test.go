@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func shareRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/links", middleware.AuthRequired(), CreateLinkHandler)
+	r.POST("/collections", middleware.AuthRequired(), CreateCollectionHandler)
+	r.GET("/collections/:id", middleware.AuthRequired(), GetCollectionHandler)
+	r.DELETE("/collections/:id", middleware.AuthRequired(), DeleteCollectionHandler)
+	r.POST("/shares", middleware.AuthRequired(), middleware.VerifiedRequired(), CreateShareHandler)
+	r.GET("/shares", middleware.AuthRequired(), GetSharesHandler)
+	r.DELETE("/shares/:id", middleware.AuthRequired(), DeleteShareHandler)
+	r.GET("/s/:token", PublicShareHandler)
+	return r
+}
+
+// createShare crée un partage et renvoie son id et son token
+func createShare(t *testing.T, router *gin.Engine, token string, payload interface{}) (uint, string) {
+	resp := postJSON(router, "/shares", token, payload)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var body ResponseData[struct {
+		Share ShareView `json:"share"`
+		Token string    `json:"token"`
+		URL   string    `json:"url"`
+	}]
+	assert.NoError(t, jsonDecode(resp, &body))
+	assert.True(t, strings.HasSuffix(body.Data.URL, "/s/"+body.Data.Token))
+	return body.Data.Share.ID, body.Data.Token
+}
+
+func openShare(router *gin.Engine, token, password string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/s/"+token, nil)
+	if password != "" {
+		req.Header.Set(SharePasswordHeader, password)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func sharedURLs(t *testing.T, resp *httptest.ResponseRecorder) []string {
+	var body ResponseData[struct {
+		Links []map[string]interface{} `json:"links"`
+	}]
+	assert.NoError(t, jsonDecode(resp, &body))
+	var urls []string
+	for _, link := range body.Data.Links {
+		urls = append(urls, link["url"].(string))
+	}
+	return urls
+}
+
+func TestShareLinkTagAndCollection(t *testing.T) {
+	db.SetupTestDB()
+
+	user := models.User{Email: "sharer@example.com", Password: "x"}
+	other := models.User{Email: "other@example.com", Password: "x"}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	assert.NoError(t, db.DB.Create(&other).Error)
+	token, _ := auth.CreateToken(user)
+	otherToken, _ := auth.CreateToken(other)
+
+	router := shareRouter()
+
+	resp := postJSON(router, "/collections", token, map[string]string{"name": "Reading list"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var collection ResponseData[models.Collection]
+	assert.NoError(t, jsonDecode(resp, &collection))
+	collectionID := collection.Data.ID
+
+	// La collection d'un autre utilisateur ne peut pas être utilisée
+	resp = postJSON(router, "/links", otherToken, map[string]interface{}{"url": "https://other.example", "title": "Other", "collection_id": collectionID})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", token, map[string]interface{}{"url": "https://go.dev", "title": "Go", "tags": []string{"go"}, "collection_id": collectionID}).Code)
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", token, map[string]interface{}{"url": "https://gin-gonic.com", "title": "Gin", "tags": []string{"go", "web"}}).Code)
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", token, map[string]interface{}{"url": "https://react.dev", "title": "React", "tags": []string{"web"}}).Code)
+
+	var goLink models.Link
+	assert.NoError(t, db.DB.Where("url = ?", "https://go.dev").First(&goLink).Error)
+
+	// Une seule cible par partage, et elle doit appartenir à l'utilisateur
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/shares", token, map[string]interface{}{"link_id": goLink.ID, "tag": "go"}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/shares", otherToken, map[string]interface{}{"link_id": goLink.ID}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/shares", token, map[string]interface{}{}).Code)
+
+	_, linkShare := createShare(t, router, token, map[string]interface{}{"link_id": goLink.ID})
+	_, tagShare := createShare(t, router, token, map[string]interface{}{"tag": "go"})
+	_, collectionShare := createShare(t, router, token, map[string]interface{}{"collection_id": collectionID})
+
+	resp = openShare(router, linkShare, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"https://go.dev"}, sharedURLs(t, resp))
+	// Aucun champ privé n'est exposé
+	assert.NotContains(t, resp.Body.String(), "user_id")
+	assert.NotContains(t, resp.Body.String(), fmt.Sprintf(`"id":%d`, goLink.ID))
+
+	resp = openShare(router, tagShare, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.ElementsMatch(t, []string{"https://go.dev", "https://gin-gonic.com"}, sharedURLs(t, resp))
+
+	resp = openShare(router, collectionShare, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"https://go.dev"}, sharedURLs(t, resp))
+	assert.Contains(t, resp.Body.String(), "Reading list")
+
+	assert.Equal(t, http.StatusNotFound, openShare(router, "unknown", "").Code)
+
+	// Supprimer la collection révoque ses partages mais garde les liens
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/collections/%d", collectionID), token, nil).Code)
+	assert.Equal(t, http.StatusNotFound, openShare(router, collectionShare, "").Code)
+	assert.NoError(t, db.DB.First(&goLink, goLink.ID).Error)
+	assert.Nil(t, goLink.CollectionID)
+}
+
+func TestShareExpiryPasswordAndRevocation(t *testing.T) {
+	db.SetupTestDB()
+
+	user := models.User{Email: "sharer@example.com", Password: "x"}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	link := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`[]`), UserID: user.ID}
+	assert.NoError(t, db.DB.Create(&link).Error)
+	token, _ := auth.CreateToken(user)
+
+	router := shareRouter()
+
+	past := time.Now().Add(-time.Hour)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/shares", token, map[string]interface{}{"link_id": link.ID, "expires_at": past}).Code)
+
+	future := time.Now().Add(time.Hour)
+	_, expiring := createShare(t, router, token, map[string]interface{}{"link_id": link.ID, "expires_at": future})
+	assert.Equal(t, http.StatusOK, openShare(router, expiring, "").Code)
+	assert.NoError(t, db.DB.Model(&models.Share{}).Where("token_hash = ?", auth.HashToken(expiring)).Update("expires_at", past).Error)
+	assert.Equal(t, http.StatusNotFound, openShare(router, expiring, "").Code)
+
+	id, protected := createShare(t, router, token, map[string]interface{}{"link_id": link.ID, "password": "secret123"})
+	assert.Equal(t, http.StatusUnauthorized, openShare(router, protected, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, openShare(router, protected, "wrong").Code)
+	assert.Equal(t, http.StatusOK, openShare(router, protected, "secret123").Code)
+
+	resp := sendJSON(router, "GET", "/shares", token, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var list ResponseData[[]map[string]interface{}]
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+	assert.NotContains(t, resp.Body.String(), protected, "the token is only shown at creation")
+
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/shares/%d", id), token, nil).Code)
+	assert.Equal(t, http.StatusNotFound, openShare(router, protected, "secret123").Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "DELETE", fmt.Sprintf("/shares/%d", id), token, nil).Code)
+}
@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/feedreader"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	feedClientOnce sync.Once
+	feedHTTPClient *http.Client
+
+	// allowPrivateFeeds lets the tests fetch their feeds from a local server
+	allowPrivateFeeds = false
+)
+
+var errPrivateAddress = errors.New("feeds on a private address are not fetched")
+
+// feedClient returns the client fetching the subscribed feeds, built once from the config
+// since the poller and the requests use it concurrently
+func feedClient() *http.Client {
+	feedClientOnce.Do(func() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// Sans proxy : c'est l'adresse du flux qui doit être vérifiée
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   publicAddressOnly,
+		}).DialContext
+		feedHTTPClient = &http.Client{
+			Timeout:   config.Duration("FEED_FETCH_TIMEOUT", 30*time.Second),
+			Transport: transport,
+		}
+	})
+	return feedHTTPClient
+}
+
+// publicAddressOnly refuses to connect to the server itself or to the local network. It runs
+// on the resolved address, so a host name pointing there, even after a DNS change, is refused too.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	if allowPrivateFeeds {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+var errSubscriptionAccess = errors.New("no longer allowed to add links to the workspace")
+
+type CreateSubscriptionInput struct {
+	URL   string   `json:"url" binding:"required,url"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+type UpdateSubscriptionInput struct {
+	Title *string   `json:"title"`
+	Tags  *[]string `json:"tags"`
+}
+
+// subscriptionTags removes the empty and duplicated tags
+func subscriptionTags(candidates []string) []byte {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range candidates {
+		tag = strings.TrimSpace(tag)
+		if key := strings.ToLower(tag); tag != "" && !seen[key] {
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+	tagsJSON, _ := json.Marshal(tags)
+	return tagsJSON
+}
+
+// CreateSubscriptionHandler follows a feed: its entries are saved as personal links, or as
+// links of the workspace_id, at the next poll
+func CreateSubscriptionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	_, workspaceID, ok := linkScope(c, user, accessEdit)
+	if !ok {
+		return
+	}
+
+	var input CreateSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validBookmarkURL(input.URL) {
+		ErrorResponse(c, http.StatusBadRequest, "url must be an http or https URL")
+		return
+	}
+
+	existing := db.DB.Model(&models.Subscription{}).Where("user_id = ? AND url = ?", user.ID, input.URL)
+	if workspaceID == nil {
+		existing = existing.Where("workspace_id IS NULL")
+	} else {
+		existing = existing.Where("workspace_id = ?", *workspaceID)
+	}
+	var count int64
+	if existing.Count(&count); count > 0 {
+		ErrorResponse(c, http.StatusConflict, "Already subscribed to this feed")
+		return
+	}
+
+	subscription := models.Subscription{
+		UserID:      user.ID,
+		WorkspaceID: workspaceID,
+		URL:         input.URL,
+		Title:       strings.TrimSpace(input.Title),
+		Tags:        subscriptionTags(input.Tags),
+		Status:      models.SubscriptionPending,
+	}
+	if err := db.DB.Create(&subscription).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not save the subscription")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, subscription)
+}
+
+// GetSubscriptionsHandler lists the subscriptions of the user with the status of their last poll
+func GetSubscriptionsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscriptions := []models.Subscription{}
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&subscriptions).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch subscriptions")
+		return
+	}
+	SuccessResponse(c, http.StatusOK, subscriptions)
+}
+
+// subscriptionForRequest loads a subscription of the user, other subscriptions answer 404
+func subscriptionForRequest(c *gin.Context, user models.User) (models.Subscription, bool) {
+	var subscription models.Subscription
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&subscription).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Subscription not found")
+		return subscription, false
+	}
+	return subscription, true
+}
+
+func GetSubscriptionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, ok := subscriptionForRequest(c, user)
+	if !ok {
+		return
+	}
+	SuccessResponse(c, http.StatusOK, subscription)
+}
+
+// UpdateSubscriptionHandler renames the subscription or changes the tags of the next links
+func UpdateSubscriptionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, ok := subscriptionForRequest(c, user)
+	if !ok {
+		return
+	}
+
+	var input UpdateSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Title != nil {
+		updates["title"] = strings.TrimSpace(*input.Title)
+	}
+	if input.Tags != nil {
+		updates["tags"] = subscriptionTags(*input.Tags)
+	}
+	if err := db.DB.Model(&subscription).Updates(updates).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update the subscription")
+		return
+	}
+	SuccessResponse(c, http.StatusOK, subscription)
+}
+
+// deleteSubscriptions deletes the subscriptions matching the condition and their seen entries
+func deleteSubscriptions(tx *gorm.DB, query string, args ...interface{}) error {
+	ids := tx.Model(&models.Subscription{}).Select("id").Where(query, args...)
+	if err := tx.Where("subscription_id IN (?)", ids).Delete(&models.SubscriptionEntry{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(query, args...).Delete(&models.Subscription{}).Error
+}
+
+// DeleteSubscriptionHandler stops following the feed, the saved links are kept
+func DeleteSubscriptionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, ok := subscriptionForRequest(c, user)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteSubscriptions(tx, "id = ?", subscription.ID)
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete the subscription")
+		return
+	}
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Subscription deleted"})
+}
+
+// PollSubscriptionHandler polls the feed right away and returns the updated subscription
+func PollSubscriptionHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, ok := subscriptionForRequest(c, user)
+	if !ok {
+		return
+	}
+
+	subscription = pollSubscription(subscription)
+	SuccessResponse(c, http.StatusOK, subscription)
+}
+
+// pollSubscription fetches the feed and saves its new entries. The outcome is kept on the
+// subscription, an error doesn't stop the next polls.
+func pollSubscription(subscription models.Subscription) models.Subscription {
+	now := time.Now()
+	subscription.LastPolledAt = &now
+
+	created, result, err := saveFeedEntries(subscription)
+	subscription.Saved += created
+	if err != nil {
+		subscription.Status = models.SubscriptionError
+		subscription.LastError = err.Error()
+	} else {
+		subscription.Status = models.SubscriptionOK
+		subscription.LastError = ""
+		subscription.LastSuccessAt = &now
+		subscription.ETag = result.ETag
+		subscription.LastModified = result.LastModified
+		if subscription.Title == "" {
+			subscription.Title = result.Title
+		}
+	}
+
+	if err := db.DB.Model(&subscription).Select(
+		"Status", "LastPolledAt", "LastSuccessAt", "LastError", "ETag", "LastModified", "Saved", "Title",
+	).Updates(&subscription).Error; err != nil {
+		logger.ErrorLogger.Println("Could not save the poll of a subscription:", err)
+	}
+	return subscription
+}
+
+// saveFeedEntries saves the entries of the feed not seen yet, oldest first, and returns the
+// number of links created. Entries whose URL is already saved are only marked as seen.
+func saveFeedEntries(subscription models.Subscription) (int, feedreader.Result, error) {
+	var user models.User
+	if err := db.DB.First(&user, subscription.UserID).Error; err != nil {
+		return 0, feedreader.Result{}, err
+	}
+	scope := db.DB.Where("user_id = ? AND workspace_id IS NULL", user.ID)
+	if subscription.WorkspaceID != nil {
+		// Le rôle a pu changer depuis l'abonnement
+		if roleAccess(workspaceRole(user.ID, *subscription.WorkspaceID)) < accessEdit {
+			return 0, feedreader.Result{}, errSubscriptionAccess
+		}
+		scope = db.DB.Where("workspace_id = ?", *subscription.WorkspaceID)
+	}
+
+	result, err := feedreader.Fetch(feedClient(), subscription.URL, subscription.ETag, subscription.LastModified)
+	if err != nil || result.NotModified {
+		return 0, result, err
+	}
+
+	var seenIDs []string
+	if err := db.DB.Model(&models.SubscriptionEntry{}).Where("subscription_id = ?", subscription.ID).Pluck("entry_id", &seenIDs).Error; err != nil {
+		return 0, result, err
+	}
+	seen := map[string]bool{}
+	for _, id := range seenIDs {
+		seen[id] = true
+	}
+	saved, err := savedURLKeys(scope)
+	if err != nil {
+		return 0, result, err
+	}
+
+	created := 0
+	// Les flux listent les entrées les plus récentes en premier
+	for i := len(result.Items) - 1; i >= 0; i-- {
+		item := result.Items[i]
+		if item.ID == "" || seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+
+		link := models.Link{
+			URL:         item.URL,
+			Title:       item.Title,
+			Tags:        subscription.Tags,
+			UserID:      user.ID,
+			WorkspaceID: subscription.WorkspaceID,
+		}
+		if link.Title == "" {
+			link.Title = item.URL
+		}
+		newLink := validBookmarkURL(item.URL) && !saved[urlnorm.Normalize(item.URL)]
+
+		if err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&models.SubscriptionEntry{SubscriptionID: subscription.ID, EntryID: item.ID}).Error; err != nil {
+				return err
+			}
+			if !newLink {
+				return nil
+			}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+			return saveRevision(tx, nil, link, user, nil)
+		}); err != nil {
+			return created, result, err
+		}
+		if newLink {
+			saved[link.NormalizedURL] = true
+			created++
+			waitScrape(link.ID, link.URL)
+		}
+	}
+
+	if created > 0 {
+		event := models.AuditEvent{ActorID: &user.ID, Action: "link.subscription", TargetType: "link", WorkspaceID: subscription.WorkspaceID}
+		if subscription.WorkspaceID == nil {
+			event.OwnerID = &user.ID
+		}
+		audit.Change(event, nil, gin.H{"subscription_id": subscription.ID, "created": created})
+	}
+	return created, result, nil
+}
+
+// PollSubscriptionsEvery polls, every minute, the subscriptions not polled for an interval.
+// The subscriptions of disabled accounts are left alone.
+func PollSubscriptionsEvery(interval time.Duration) {
+	for {
+		var subscriptions []models.Subscription
+		err := db.DB.Where("last_polled_at IS NULL OR last_polled_at < ?", time.Now().Add(-interval)).
+			Where("user_id IN (?)", db.DB.Model(&models.User{}).Select("id").Where("disabled_at IS NULL")).
+			Order("last_polled_at").Find(&subscriptions).Error
+		if err != nil {
+			logger.ErrorLogger.Println("Could not fetch the subscriptions to poll:", err)
+		}
+		for _, subscription := range subscriptions {
+			if polled := pollSubscription(subscription); polled.Status == models.SubscriptionError {
+				logger.ErrorLogger.Printf("Could not poll subscription %d: %s", polled.ID, polled.LastError)
+			}
+		}
+		time.Sleep(time.Minute)
+	}
+}
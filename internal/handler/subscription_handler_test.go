@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func subscriptionRouter() *gin.Engine {
+	r := gin.Default()
+	r.GET("/links", middleware.AuthRequired(), GetLinksHandler)
+	r.DELETE("/link/:id", middleware.AuthRequired(), DeleteLinkHandler)
+	r.POST("/subscriptions", middleware.AuthRequired(), CreateSubscriptionHandler)
+	r.GET("/subscriptions", middleware.AuthRequired(), GetSubscriptionsHandler)
+	r.GET("/subscriptions/:id", middleware.AuthRequired(), GetSubscriptionHandler)
+	r.PUT("/subscriptions/:id", middleware.AuthRequired(), UpdateSubscriptionHandler)
+	r.DELETE("/subscriptions/:id", middleware.AuthRequired(), DeleteSubscriptionHandler)
+	r.POST("/subscriptions/:id/poll", middleware.AuthRequired(), PollSubscriptionHandler)
+	return r
+}
+
+// feedServer sert un flux RSS dont les entrées peuvent changer, avec un ETag par version
+type feedServer struct {
+	mu       sync.Mutex
+	items    []string
+	requests int
+}
+
+func (s *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	etag := fmt.Sprintf(`"%d"`, len(s.items))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, `<rss version="2.0"><channel><title>Team news</title>`)
+	// Les plus récentes en premier
+	for i := len(s.items) - 1; i >= 0; i-- {
+		fmt.Fprintf(w, `<item><title>Item %d</title><link>%s</link><guid>item-%d</guid></item>`, i, s.items[i], i)
+	}
+	fmt.Fprint(w, `</channel></rss>`)
+}
+
+func (s *feedServer) add(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, url)
+}
+
+func TestSubscriptionPolling(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	feed := &feedServer{items: []string{"https://example.com/first", "https://example.com/second"}}
+	server := httptest.NewServer(feed)
+	defer server.Close()
+	allowPrivateFeeds = true
+	defer func() { allowPrivateFeeds = false }()
+
+	_, tokens := workspaceUsers(t, "reader@example.com", "other@example.com")
+	token := tokens[0]
+	router := subscriptionRouter()
+
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/subscriptions", token, map[string]string{"url": "ftp://example.com/feed"}).Code)
+
+	resp := postJSON(router, "/subscriptions", token, map[string]interface{}{"url": server.URL + "/rss", "tags": []string{"news", " News ", ""}})
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created ResponseData[models.Subscription]
+	assert.NoError(t, jsonDecode(resp, &created))
+	assert.Equal(t, models.SubscriptionPending, created.Data.Status)
+	assert.JSONEq(t, `["news"]`, string(created.Data.Tags))
+	assert.Equal(t, http.StatusConflict, postJSON(router, "/subscriptions", token, map[string]string{"url": server.URL + "/rss"}).Code)
+
+	path := fmt.Sprintf("/subscriptions/%d", created.Data.ID)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, tokens[1], nil).Code)
+
+	var polled ResponseData[models.Subscription]
+	assert.NoError(t, jsonDecode(postJSON(router, path+"/poll", token, nil), &polled))
+	assert.Equal(t, models.SubscriptionOK, polled.Data.Status)
+	assert.Equal(t, "Team news", polled.Data.Title)
+	assert.Equal(t, 2, polled.Data.Saved)
+	assert.NotNil(t, polled.Data.LastSuccessAt)
+
+	var links ResponseData[[]models.Link]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links", token, nil), &links))
+	assert.Len(t, links.Data, 2)
+	assert.Equal(t, "https://example.com/first", links.Data[0].URL)
+	assert.JSONEq(t, `["news"]`, string(links.Data[0].Tags))
+
+	// Rien de nouveau : le serveur répond 304
+	assert.NoError(t, jsonDecode(postJSON(router, path+"/poll", token, nil), &polled))
+	assert.Equal(t, models.SubscriptionOK, polled.Data.Status)
+	assert.Equal(t, 2, polled.Data.Saved)
+
+	// Un lien supprimé n'est pas réenregistré au relevé suivant
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/link/%d", links.Data[0].ID), token, nil).Code)
+	feed.add("https://example.com/third")
+	assert.NoError(t, jsonDecode(postJSON(router, path+"/poll", token, nil), &polled))
+	assert.Equal(t, 3, polled.Data.Saved)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links", token, nil), &links))
+	assert.Len(t, links.Data, 2)
+	assert.Equal(t, "https://example.com/third", links.Data[1].URL)
+
+	resp = sendJSON(router, "PUT", path, token, map[string]interface{}{"tags": []string{"team"}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"tags":["team"]`)
+
+	// Les erreurs restent visibles sur l'abonnement
+	resp = postJSON(router, "/subscriptions", token, map[string]string{"url": server.URL + "/missing", "title": "Broken"})
+	assert.NoError(t, jsonDecode(resp, &created))
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	assert.NoError(t, jsonDecode(postJSON(router, fmt.Sprintf("/subscriptions/%d/poll", created.Data.ID), token, nil), &polled))
+	assert.Equal(t, models.SubscriptionError, polled.Data.Status)
+	assert.True(t, strings.Contains(polled.Data.LastError, "410"), polled.Data.LastError)
+
+	var listed ResponseData[[]models.Subscription]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/subscriptions", token, nil), &listed))
+	assert.Len(t, listed.Data, 2)
+	assert.Equal(t, "Broken", listed.Data[1].Title)
+
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", path, token, nil).Code)
+	var entries int64
+	db.DB.Model(&models.SubscriptionEntry{}).Count(&entries)
+	assert.Zero(t, entries)
+}
+
+func TestSubscriptionPrivateAddressRefused(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	server := httptest.NewServer(&feedServer{items: []string{"https://example.com/first"}})
+	defer server.Close()
+
+	_, tokens := workspaceUsers(t, "private@example.com")
+	router := subscriptionRouter()
+
+	var created ResponseData[models.Subscription]
+	assert.NoError(t, jsonDecode(postJSON(router, "/subscriptions", tokens[0], map[string]string{"url": server.URL + "/rss"}), &created))
+	var polled ResponseData[models.Subscription]
+	assert.NoError(t, jsonDecode(postJSON(router, fmt.Sprintf("/subscriptions/%d/poll", created.Data.ID), tokens[0], nil), &polled))
+	assert.Equal(t, models.SubscriptionError, polled.Data.Status)
+	assert.Contains(t, polled.Data.LastError, errPrivateAddress.Error())
+	assert.Zero(t, polled.Data.Saved)
+
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "10.0.0.8:80", "192.168.1.1:80", "169.254.169.254:80", "0.0.0.0:80"} {
+		assert.ErrorIs(t, publicAddressOnly("tcp", address, nil), errPrivateAddress, address)
+	}
+	assert.NoError(t, publicAddressOnly("tcp", "93.184.216.34:443", nil))
+}
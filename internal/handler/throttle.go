@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
+	"github.com/gin-gonic/gin"
+)
+
+// tooManyAttempts answers 429 with the time to wait before the next attempt
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+}
+
+// loginKeys returns the failure counters of a login: per account and per IP
+func loginKeys(c *gin.Context, email string) []string {
+	return []string{throttle.AccountKey(email), throttle.IPKey(c.ClientIP())}
+}
+
+// loginFailed records the failure on every key, audits new lockouts and answers 401
+func loginFailed(c *gin.Context, keys []string) {
+	recordLoginFailure(c, keys)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+}
+
+// recordLoginFailure records the failure on every key and audits new lockouts
+func recordLoginFailure(c *gin.Context, keys []string) {
+	for _, key := range throttle.RecordFailure(keys...) {
+		kind, value, _ := strings.Cut(key, ":")
+		audit.Record(models.AuditEvent{
+			Action:     "auth.lockout",
+			TargetType: kind,
+			TargetID:   value,
+			IP:         c.ClientIP(),
+		}, gin.H{"lockout": throttle.PolicyFor(key).Lockout.String()})
+	}
+}
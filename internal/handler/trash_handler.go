@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/audit"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashedLinkView is a deleted link with the date it will be purged
+type TrashedLinkView struct {
+	Link      models.Link `json:"link"`
+	DeletedAt time.Time   `json:"deleted_at"`
+	PurgeAt   time.Time   `json:"purge_at"`
+}
+
+// trashRetention is how long deleted links stay in the trash
+func trashRetention() time.Duration {
+	return config.Duration("TRASH_RETENTION", 30*24*time.Hour)
+}
+
+// trashedLink loads the deleted link of the URL. The trash belongs to the library of the link:
+// the owner of a personal link, or the editors of the workspace. Grants don't apply.
+func trashedLink(c *gin.Context, user models.User) (models.Link, bool) {
+	var link models.Link
+	if err := db.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", c.Param("id")).First(&link).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Link not found in trash")
+		return link, false
+	}
+
+	access := accessNone
+	if link.WorkspaceID != nil {
+		access = roleAccess(workspaceRole(user.ID, *link.WorkspaceID))
+	} else if link.UserID == user.ID {
+		access = accessEdit
+	}
+	if access == accessNone {
+		ErrorResponse(c, http.StatusNotFound, "Link not found in trash")
+		return link, false
+	}
+	if access < accessEdit {
+		ErrorResponse(c, http.StatusForbidden, "Read-only access")
+		return link, false
+	}
+	return link, true
+}
+
+// GetTrashHandler lists the deleted personal links, or those of the workspace_id
+func GetTrashHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	query, _, ok := linkScope(c, user, accessView)
+	if !ok {
+		return
+	}
+
+	var links []models.Link
+	if err := query.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch the trash")
+		return
+	}
+
+	retention := trashRetention()
+	views := make([]TrashedLinkView, 0, len(links))
+	for _, link := range links {
+		views = append(views, TrashedLinkView{
+			Link:      link,
+			DeletedAt: link.DeletedAt.Time,
+			PurgeAt:   link.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+// RestoreLinkHandler takes a link out of the trash
+func RestoreLinkHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := trashedLink(c, user)
+	if !ok {
+		return
+	}
+
+	// La même page a pu être enregistrée à nouveau depuis la suppression
+	if existing, found := duplicateOf(libraryOf(link), link.URL, link.ID); found {
+		duplicateResponse(c, existing)
+		return
+	}
+	if err := db.DB.Unscoped().Model(&link).Update("deleted_at", nil).Error; err != nil {
+		saveFailed(c, link, "Could not restore the link")
+		return
+	}
+	link.DeletedAt = gorm.DeletedAt{}
+	recordLinkChange(c, user, "link.untrash", nil, &link)
+
+	counted := []models.Link{link}
+	withCommentCounts(counted)
+	SuccessResponse(c, http.StatusOK, counted[0])
+}
+
+// PurgeLinkHandler permanently deletes a link of the trash with its comments, shares and revisions
+func PurgeLinkHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	link, ok := trashedLink(c, user)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeLinks(tx, []uint{link.ID})
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not purge the link")
+		return
+	}
+	recordLinkChange(c, user, "link.purge", &link, nil)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Link purged"})
+}
+
+// EmptyTrashHandler permanently deletes the personal links of the trash, or those of the workspace_id
+func EmptyTrashHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	query, _, ok := linkScope(c, user, accessEdit)
+	if !ok {
+		return
+	}
+
+	var links []models.Link
+	if err := query.Unscoped().Where("deleted_at IS NOT NULL").Find(&links).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch the trash")
+		return
+	}
+
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeLinks(tx, ids)
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not empty the trash")
+		return
+	}
+	for i := range links {
+		recordLinkChange(c, user, "link.purge", &links[i], nil)
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"purged": len(ids)})
+}
+
+// PurgeExpiredTrash permanently deletes the links deleted for longer than the retention period
+func PurgeExpiredTrash() (int, error) {
+	var ids []uint
+	cutoff := time.Now().Add(-trashRetention())
+	if err := db.DB.Unscoped().Model(&models.Link{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeLinks(tx, ids)
+	}); err != nil {
+		return 0, err
+	}
+	audit.Record(models.AuditEvent{Action: "trash.purge_expired", TargetType: "link"}, gin.H{"links": ids})
+	return len(ids), nil
+}
+
+// PurgeTrashEvery runs PurgeExpiredTrash at each interval, it never returns.
+// An interval below a minute is raised to a minute.
+func PurgeTrashEvery(interval time.Duration) {
+	// Un intervalle nul ou négatif ferait tourner la purge en boucle
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	for {
+		if purged, err := PurgeExpiredTrash(); err != nil {
+			logger.ErrorLogger.Println("Could not purge the trash:", err)
+		} else if purged > 0 {
+			logger.InfoLogger.Printf("Purged %d links from the trash", purged)
+		}
+		time.Sleep(interval)
+	}
+}
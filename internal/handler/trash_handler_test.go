@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func trashRouter() *gin.Engine {
+	r := workspaceRouter()
+	r.POST("/link/:id/restore", middleware.AuthRequired(), RestoreLinkHandler)
+	r.GET("/trash", middleware.AuthRequired(), GetTrashHandler)
+	r.DELETE("/trash", middleware.AuthRequired(), EmptyTrashHandler)
+	r.DELETE("/trash/:id", middleware.AuthRequired(), PurgeLinkHandler)
+	return r
+}
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "other@example.com")
+	owner, other := tokens[0], tokens[1]
+	router := trashRouter()
+
+	var links []models.Link
+	for _, title := range []string{"Go", "Rust", "Zig"} {
+		link := models.Link{URL: "https://example.com/" + title, Title: title, Tags: []byte(`[]`), UserID: users[0].ID}
+		assert.NoError(t, db.DB.Create(&link).Error)
+		assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/link/%d", link.ID), owner, nil).Code)
+		links = append(links, link)
+	}
+	assert.NoError(t, db.DB.Create(&models.Comment{LinkID: links[1].ID, UserID: users[0].ID, Body: "Fast"}).Error)
+
+	var trash ResponseData[[]TrashedLinkView]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/trash", owner, nil), &trash))
+	assert.Len(t, trash.Data, 3)
+	assert.WithinDuration(t, trash.Data[0].DeletedAt.Add(30*24*time.Hour), trash.Data[0].PurgeAt, time.Second)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/trash", other, nil), &trash))
+	assert.Empty(t, trash.Data)
+
+	// Un droit d'édition sur le lien ne donne pas accès à la corbeille du propriétaire
+	assert.NoError(t, db.DB.Create(&models.Grant{OwnerID: users[0].ID, UserID: users[1].ID, LinkID: &links[0].ID, Permission: models.PermissionEdit}).Error)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "DELETE", fmt.Sprintf("/trash/%d", links[0].ID), other, nil).Code)
+
+	// Restaurer remet le lien dans la liste
+	restore := fmt.Sprintf("/link/%d/restore", links[0].ID)
+	assert.Equal(t, http.StatusNotFound, postJSON(router, restore, other, nil).Code)
+	assert.Equal(t, http.StatusOK, postJSON(router, restore, owner, nil).Code)
+	assert.Equal(t, http.StatusNotFound, postJSON(router, restore, owner, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", fmt.Sprintf("/link/%d", links[0].ID), owner, nil).Code)
+
+	// Suppression définitive d'un lien, avec ses commentaires
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "DELETE", fmt.Sprintf("/trash/%d", links[0].ID), owner, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/trash/%d", links[1].ID), owner, nil).Code)
+	var count int64
+	db.DB.Unscoped().Model(&models.Comment{}).Where("link_id = ?", links[1].ID).Count(&count)
+	assert.Zero(t, count)
+
+	resp := sendJSON(router, "DELETE", "/trash", owner, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"purged":1`)
+	db.DB.Unscoped().Model(&models.Link{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	db.SetupTestDB()
+	t.Setenv("TRASH_RETENTION", "24h")
+
+	user := models.User{Email: "owner@example.com", Password: "x"}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	expired := models.Link{URL: "https://go.dev", Title: "Go", Tags: []byte(`[]`), UserID: user.ID}
+	recent := models.Link{URL: "https://zig.dev", Title: "Zig", Tags: []byte(`[]`), UserID: user.ID}
+	assert.NoError(t, db.DB.Create(&expired).Error)
+	assert.NoError(t, db.DB.Create(&recent).Error)
+	assert.NoError(t, db.DB.Unscoped().Model(&expired).Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+	assert.NoError(t, db.DB.Delete(&recent).Error)
+
+	purged, err := PurgeExpiredTrash()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var count int64
+	db.DB.Unscoped().Model(&models.Link{}).Where("id = ?", recent.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
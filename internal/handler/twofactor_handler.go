@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/DebroyeAntoine/go_link_vault/internal/throttle"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer           = "Go Link Vault"
+	recoveryCodeCount    = 10
+	challengeTTL         = 5 * time.Minute
+	challengeMaxAttempts = 5
+)
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorLoginInput struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// normalizeRecoveryCode ignore la casse et les séparateurs saisis par l'utilisateur
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes replaces the recovery codes of the user and returns them in clear, only once
+func generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: auth.HashToken(raw)})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkTOTP validates a TOTP code and refuses codes already used
+func checkTOTP(user *models.User, code string) bool {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false
+	}
+
+	// Mise à jour conditionnelle : deux requêtes concurrentes ne peuvent pas consommer le même pas
+	result := db.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	user.TOTPLastStep = step
+	return true
+}
+
+// useRecoveryCode consumes a recovery code of the user
+func useRecoveryCode(user *models.User, code string) bool {
+	result := db.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code
+func checkSecondFactor(user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		return checkTOTP(user, code)
+	}
+	if recoveryCode != "" {
+		return useRecoveryCode(user, recoveryCode)
+	}
+	return false
+}
+
+// createTwoFactorChallenge stores a short-lived challenge returned by the login instead of the token
+func createTwoFactorChallenge(user models.User, cookie bool) (string, error) {
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(challengeTTL),
+
+		CookieSession: cookie,
+	}
+	if err := db.DB.Create(&challenge).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// EnrollTwoFactorHandler generates a new TOTP secret. It is only active once confirmed with a code.
+func EnrollTwoFactorHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not generate secret")
+		return
+	}
+
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not save secret")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, user.Email, totpIssuer),
+	})
+}
+
+// ConfirmTwoFactorHandler enables 2FA once the user proves the authenticator is set up
+func ConfirmTwoFactorHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication already enabled")
+		return
+	}
+	if user.TOTPSecret == "" {
+		ErrorResponse(c, http.StatusBadRequest, "Two-factor enrolment not started")
+		return
+	}
+
+	if !checkTOTP(&user, input.Code) {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	codes, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not generate recovery codes")
+		return
+	}
+
+	if err := db.DB.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not enable two-factor authentication")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactorHandler turns 2FA off, requiring the password and a second factor
+func DisableTwoFactorHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication not enabled")
+		return
+	}
+	if !auth.CheckPasswordHash(input.Password, user.Password) || !checkSecondFactor(&user, input.Code, input.RecoveryCode) {
+		ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"two_factor_enabled": false,
+		"totp_secret":        "",
+		"totp_last_step":     0,
+	}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not disable two-factor authentication")
+		return
+	}
+	db.DB.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodesHandler invalidates the previous recovery codes and returns new ones
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication not enabled")
+		return
+	}
+	if !checkTOTP(&user, input.Code) {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	codes, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not generate recovery codes")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginTwoFactorHandler completes a login challenge with a TOTP or recovery code and issues the token
+func LoginTwoFactorHandler(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var challenge models.TwoFactorChallenge
+	if err := db.DB.Where("token_hash = ?", auth.HashToken(input.Challenge)).First(&challenge).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= challengeMaxAttempts {
+		db.DB.Unscoped().Delete(&challenge)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, challenge.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	// Mêmes compteurs que le mot de passe : redemander un challenge ne remet pas à zéro
+	keys := loginKeys(c, user.Email)
+	if wait := throttle.Blocked(keys...); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	if !checkSecondFactor(&user, input.Code, input.RecoveryCode) {
+		db.DB.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1"))
+		recordLoginFailure(c, keys)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+	db.DB.Unscoped().Delete(&challenge)
+
+	if user.IsDisabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	}
+
+	if issueSession(c, user, challenge.CookieSession) {
+		throttle.Reset(keys[0])
+	}
+}
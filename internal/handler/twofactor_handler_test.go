@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorFlow(t *testing.T) {
+	db.SetupTestDB()
+
+	hashedPwd, _ := auth.HashPassword("password123")
+	user := models.User{Email: "2fa@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	token, _ := auth.CreateToken(user)
+
+	router := gin.Default()
+	router.POST("/login", LoginUserHandler)
+	router.POST("/login/2fa", LoginTwoFactorHandler)
+	router.POST("/2fa/enroll", middleware.AuthRequired(), EnrollTwoFactorHandler)
+	router.POST("/2fa/confirm", middleware.AuthRequired(), ConfirmTwoFactorHandler)
+
+	// Enrôlement
+	resp := postJSON(router, "/2fa/enroll", token, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var enroll ResponseData[map[string]string]
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &enroll))
+	secret := enroll.Data["secret"]
+	assert.NotEmpty(t, secret)
+	assert.Contains(t, enroll.Data["otpauth_uri"], "otpauth://totp/")
+
+	// Confirmation avec un code valide
+	now := time.Now()
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(now))
+	resp = postJSON(router, "/2fa/confirm", token, map[string]string{"code": code})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var confirm ResponseData[map[string][]string]
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &confirm))
+	assert.Len(t, confirm.Data["recovery_codes"], recoveryCodeCount)
+
+	// Le login renvoie un challenge au lieu du token
+	resp = postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": "password123"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var login map[string]interface{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &login))
+	assert.Equal(t, true, login["two_factor_required"])
+	assert.Nil(t, login["token"])
+	challenge := login["challenge"].(string)
+
+	// Le code déjà utilisé pour la confirmation est refusé (rejeu)
+	resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "code": code})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// Un code de récupération termine la connexion
+	resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "recovery_code": confirm.Data["recovery_codes"][0]})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var final map[string]string
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &final))
+	assert.NotEmpty(t, final["token"])
+
+	// Le challenge et le code de récupération ne sont utilisables qu'une fois
+	resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "recovery_code": confirm.Data["recovery_codes"][0]})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestTwoFactorLoginWithTOTP(t *testing.T) {
+	db.SetupTestDB()
+
+	secret, _ := auth.GenerateTOTPSecret()
+	hashedPwd, _ := auth.HashPassword("password123")
+	user := models.User{Email: "totp@example.com", Password: hashedPwd, TOTPSecret: secret, TwoFactorEnabled: true}
+	assert.NoError(t, db.DB.Create(&user).Error)
+
+	router := gin.Default()
+	router.POST("/login", LoginUserHandler)
+	router.POST("/login/2fa", LoginTwoFactorHandler)
+
+	resp := postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": "password123"})
+	var login map[string]interface{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &login))
+	challenge := login["challenge"].(string)
+
+	resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "code": code})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestTwoFactorFailuresThrottled(t *testing.T) {
+	db.SetupTestDB()
+
+	secret, _ := auth.GenerateTOTPSecret()
+	hashedPwd, _ := auth.HashPassword("password123")
+	user := models.User{Email: "brute@example.com", Password: hashedPwd, TOTPSecret: secret, TwoFactorEnabled: true}
+	assert.NoError(t, db.DB.Create(&user).Error)
+
+	router := gin.Default()
+	router.POST("/login", LoginUserHandler)
+	router.POST("/login/2fa", LoginTwoFactorHandler)
+
+	// Un nouveau challenge à chaque essai ne contourne pas le verrouillage du compte
+	throttled := false
+	for i := 0; i < 10 && !throttled; i++ {
+		resp := postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": "password123"})
+		if resp.Code == http.StatusTooManyRequests {
+			throttled = true
+			break
+		}
+		var login map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &login))
+		challenge, _ := login["challenge"].(string)
+
+		resp = postJSON(router, "/login/2fa", "", map[string]string{"challenge": challenge, "code": "000000"})
+		throttled = resp.Code == http.StatusTooManyRequests
+	}
+	assert.True(t, throttled)
+}
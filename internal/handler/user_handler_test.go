@@ -9,6 +9,7 @@ import (
 
 	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
 	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
 	"github.com/DebroyeAntoine/go_link_vault/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -37,6 +38,16 @@ func TestRegisterUser(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &response)
 
 	assert.NotEmpty(t, response["token"])
+
+	// L'adresse est déjà prise, quelle que soit sa casse
+	resp = postJSON(router, "/register", "", map[string]string{"email": "NewUser@example.com", "password": "otherpass123"})
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	var count int64
+	db.DB.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// L'index unique arrête aussi les comptes créés sans passer par la vérification
+	assert.Error(t, db.DB.Create(&models.User{Email: "NEWUSER@example.com", Password: "x"}).Error)
 }
 
 func TestLoginUser(t *testing.T) {
@@ -74,3 +85,63 @@ func TestLoginUser(t *testing.T) {
 
 	assert.NotEmpty(t, response["token"])
 }
+
+func TestLoginLockout(t *testing.T) {
+	db.SetupTestDB()
+	t.Setenv("LOGIN_FREE_ATTEMPTS", "5")
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+
+	hashedpwd, _ := auth.HashPassword("rightpassword")
+	user := models.User{Email: "victim@example.com", Password: hashedpwd}
+	admin := models.User{Email: "admin@example.com", Password: hashedpwd, Role: models.RoleAdmin}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	assert.NoError(t, db.DB.Create(&admin).Error)
+	adminToken, _ := auth.CreateToken(admin)
+
+	router := gin.Default()
+	router.POST("/login", LoginUserHandler)
+	router.POST("/admin/unlock", middleware.AuthRequired(), middleware.AdminRequired(), UnlockLoginHandler)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		return postJSON(router, "/login", "", map[string]string{"email": user.Email, "password": password})
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("wrongpassword").Code)
+	}
+
+	// Le compte est verrouillé, même avec le bon mot de passe
+	resp := login("rightpassword")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+
+	var events int64
+	db.DB.Model(&models.AuditEvent{}).Where("action = ?", "auth.lockout").Count(&events)
+	assert.Equal(t, int64(1), events)
+
+	// Un utilisateur standard ne peut pas déverrouiller
+	userToken, _ := auth.CreateToken(user)
+	resp = postJSON(router, "/admin/unlock", userToken, map[string]string{"email": user.Email, "ip": "127.0.0.1"})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = postJSON(router, "/admin/unlock", adminToken, map[string]string{"email": user.Email, "ip": "192.0.2.1"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.Equal(t, http.StatusOK, login("rightpassword").Code)
+}
+
+func TestLoginUnknownEmailIsThrottled(t *testing.T) {
+	db.SetupTestDB()
+	t.Setenv("LOGIN_FREE_ATTEMPTS", "5")
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "2")
+
+	router := gin.Default()
+	router.POST("/login", LoginUserHandler)
+
+	payload := map[string]string{"email": "nobody@example.com", "password": "whatever"}
+	assert.Equal(t, http.StatusUnauthorized, postJSON(router, "/login", "", payload).Code)
+	assert.Equal(t, http.StatusUnauthorized, postJSON(router, "/login", "", payload).Code)
+
+	// Même comportement qu'un compte existant : on ne peut pas deviner les emails inscrits
+	assert.Equal(t, http.StatusTooManyRequests, postJSON(router, "/login", "", payload).Code)
+}
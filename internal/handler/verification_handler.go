@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// sendVerificationEmail creates a verification token for the given address and mails the link.
+// Previous pending tokens of the user are invalidated.
+func sendVerificationEmail(user models.User, email string) error {
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	db.DB.Where("user_id = ?", user.ID).Delete(&models.EmailVerification{})
+
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour)),
+	}
+	if err := db.DB.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	link := config.BaseURL() + "/verify-email?token=" + url.QueryEscape(token)
+	return mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your Go Link Vault email address",
+		Body:    "Open this link to confirm your email address:\n\n" + link + "\n\nIf you did not create an account, ignore this email.",
+	})
+}
+
+// VerifyEmailHandler confirms the address matching the token sent by email
+func VerifyEmailHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		ErrorResponse(c, http.StatusBadRequest, "Missing token")
+		return
+	}
+
+	var verification models.EmailVerification
+	if err := db.DB.Where("token_hash = ?", auth.HashToken(token)).First(&verification).Error; err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		db.DB.Delete(&verification)
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	// Cas d'un changement d'email : l'adresse a pu être prise entre-temps
+	if emailTaken(verification.Email, verification.UserID) {
+		db.DB.Delete(&verification)
+		ErrorResponse(c, http.StatusConflict, "Email already in use")
+		return
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(&models.User{}).Where("id = ?", verification.UserID).Updates(map[string]interface{}{
+		"email":             verification.Email,
+		"email_verified_at": now,
+	}).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not verify email")
+		return
+	}
+	db.DB.Delete(&verification)
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerificationHandler sends a new verification link to the logged-in user
+func ResendVerificationHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.IsVerified() {
+		ErrorResponse(c, http.StatusBadRequest, "Email already verified")
+		return
+	}
+
+	if err := sendVerificationEmail(user, user.Email); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not send verification email")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Verification email sent"})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterSendsVerificationEmail(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	router := gin.Default()
+	router.POST("/register", RegisterUserHandler)
+	router.GET("/verify-email", VerifyEmailHandler)
+
+	body, _ := json.Marshal(map[string]string{
+		"email":    "verify@example.com",
+		"password": "securepass123",
+	})
+	req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	// Le compte démarre non vérifié
+	var user models.User
+	assert.NoError(t, db.DB.Where("email = ?", "verify@example.com").First(&user).Error)
+	assert.False(t, user.IsVerified())
+
+	assert.Len(t, rec.sent, 1)
+	assert.Equal(t, "verify@example.com", rec.sent[0].To)
+	token := tokenFromMail(t, rec.sent[0])
+
+	req, _ = http.NewRequest("GET", "/verify-email?token="+url.QueryEscape(token), nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.NoError(t, db.DB.First(&user, user.ID).Error)
+	assert.True(t, user.IsVerified())
+
+	// Le token n'est utilisable qu'une fois
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestResendVerification(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	hashedPwd, _ := auth.HashPassword("password")
+	user := models.User{Email: "resend@example.com", Password: hashedPwd}
+	assert.NoError(t, db.DB.Create(&user).Error)
+	token, _ := auth.CreateToken(user)
+
+	router := gin.Default()
+	router.POST("/verify-email/resend", middleware.AuthRequired(), ResendVerificationHandler)
+
+	req, _ := http.NewRequest("POST", "/verify-email/resend", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, rec.sent, 1)
+
+	var count int64
+	db.DB.Model(&models.EmailVerification{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
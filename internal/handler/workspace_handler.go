@@ -0,0 +1,560 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type InviteInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+type MemberRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+type AcceptInvitationInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// WorkspaceView is a workspace with the role of the current user
+type WorkspaceView struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemberView struct {
+	UserID   uint      `json:"user_id"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type InvitationView struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var errLastOwner = errors.New("a workspace needs at least one owner")
+
+// workspaceForRequest loads the workspace of the URL. Non members get a 404,
+// members without the needed role a 403.
+func workspaceForRequest(c *gin.Context, user models.User, ownerOnly bool) (models.Workspace, string, bool) {
+	var workspace models.Workspace
+	if err := db.DB.Where("id = ?", c.Param("id")).First(&workspace).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Workspace not found")
+		return workspace, "", false
+	}
+
+	role := workspaceRole(user.ID, workspace.ID)
+	if role == "" {
+		ErrorResponse(c, http.StatusNotFound, "Workspace not found")
+		return workspace, "", false
+	}
+	if ownerOnly && role != models.WorkspaceOwner {
+		ErrorResponse(c, http.StatusForbidden, "Only owners can manage the workspace")
+		return workspace, role, false
+	}
+	return workspace, role, true
+}
+
+func countOwners(tx *gorm.DB, workspaceID uint) int64 {
+	var count int64
+	tx.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND role = ?", workspaceID, models.WorkspaceOwner).Count(&count)
+	return count
+}
+
+// deleteWorkspace removes the workspace with its links, members and invitations, inside tx
+func deleteWorkspace(tx *gorm.DB, workspace models.Workspace) error {
+	var linkIDs []uint
+	if err := tx.Unscoped().Model(&models.Link{}).Where("workspace_id = ?", workspace.ID).Pluck("id", &linkIDs).Error; err != nil {
+		return err
+	}
+	if err := purgeLinks(tx, linkIDs); err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.WorkspaceMember{},
+		&models.WorkspaceInvitation{},
+		&models.ImportJob{},
+	} {
+		if err := tx.Unscoped().Where("workspace_id = ?", workspace.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := deleteSubscriptions(tx, "workspace_id = ?", workspace.ID); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&workspace).Error
+}
+
+// handOverLinks attributes the links the user added to the workspace to its oldest other owner
+func handOverLinks(tx *gorm.DB, workspaceID, userID uint) error {
+	var owner models.WorkspaceMember
+	if err := tx.Where("workspace_id = ? AND role = ? AND user_id <> ?", workspaceID, models.WorkspaceOwner, userID).
+		Order("created_at").First(&owner).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.Link{}).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Update("user_id", owner.UserID).Error
+}
+
+// leaveWorkspaces removes the memberships of a deleted account. The workspaces it was the
+// last owner of are given to the oldest member, or deleted when nobody else is left.
+// Its links in the workspaces are kept and attributed to an owner.
+func leaveWorkspaces(tx *gorm.DB, user models.User) error {
+	var memberships []models.WorkspaceMember
+	if err := tx.Where("user_id = ?", user.ID).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		var others []models.WorkspaceMember
+		if err := tx.Where("workspace_id = ? AND user_id <> ?", membership.WorkspaceID, user.ID).
+			Order("created_at").Find(&others).Error; err != nil {
+			return err
+		}
+		if len(others) == 0 {
+			if err := deleteWorkspace(tx, models.Workspace{Model: gorm.Model{ID: membership.WorkspaceID}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		heir := others[0]
+		for _, other := range others {
+			if other.Role == models.WorkspaceOwner {
+				heir = other
+				break
+			}
+		}
+		if heir.Role != models.WorkspaceOwner {
+			if err := tx.Model(&heir).Update("role", models.WorkspaceOwner).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Model(&models.Link{}).Where("workspace_id = ? AND user_id = ?", membership.WorkspaceID, user.ID).
+			Update("user_id", heir.UserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&membership).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWorkspaceHandler creates a workspace owned by the current user
+func CreateWorkspaceHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input WorkspaceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	workspace := models.Workspace{Name: input.Name}
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: models.WorkspaceOwner}).Error
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create workspace")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, WorkspaceView{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		Role:      models.WorkspaceOwner,
+		CreatedAt: workspace.CreatedAt,
+	})
+}
+
+// GetWorkspacesHandler lists the workspaces of the current user with their role
+func GetWorkspacesHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	views := []WorkspaceView{}
+	if err := db.DB.Table("workspaces").
+		Select("workspaces.id, workspaces.name, workspace_members.role, workspaces.created_at").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id AND workspace_members.deleted_at IS NULL").
+		Where("workspace_members.user_id = ? AND workspaces.deleted_at IS NULL", user.ID).
+		Order("workspaces.name").
+		Scan(&views).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch workspaces")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+// GetWorkspaceHandler returns the workspace with its members
+func GetWorkspaceHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, role, ok := workspaceForRequest(c, user, false)
+	if !ok {
+		return
+	}
+
+	members := []MemberView{}
+	if err := db.DB.Table("workspace_members").
+		Select("users.id AS user_id, users.email, workspace_members.role, workspace_members.created_at AS joined_at").
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ? AND workspace_members.deleted_at IS NULL", workspace.ID).
+		Order("workspace_members.created_at").
+		Scan(&members).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch members")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{
+		"workspace": WorkspaceView{ID: workspace.ID, Name: workspace.Name, Role: role, CreatedAt: workspace.CreatedAt},
+		"members":   members,
+	})
+}
+
+func UpdateWorkspaceHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, role, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+
+	var input WorkspaceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := db.DB.Model(&workspace).Update("name", input.Name).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update workspace")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, WorkspaceView{ID: workspace.ID, Name: workspace.Name, Role: role, CreatedAt: workspace.CreatedAt})
+}
+
+// DeleteWorkspaceHandler deletes the workspace and all its links
+func DeleteWorkspaceHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteWorkspace(tx, workspace)
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete workspace")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Workspace deleted"})
+}
+
+// InviteMemberHandler emails an invitation to join the workspace with the given role
+func InviteMemberHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+
+	var input InviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	email := strings.TrimSpace(input.Email)
+
+	var existing int64
+	db.DB.Model(&models.WorkspaceMember{}).
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ? AND LOWER(users.email) = LOWER(?)", workspace.ID, email).
+		Count(&existing)
+	if existing > 0 {
+		ErrorResponse(c, http.StatusConflict, "Already a member")
+		return
+	}
+
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create invitation")
+		return
+	}
+
+	// Une nouvelle invitation remplace la précédente pour la même adresse
+	db.DB.Where("workspace_id = ? AND LOWER(email) = LOWER(?)", workspace.ID, email).Delete(&models.WorkspaceInvitation{})
+
+	invitation := models.WorkspaceInvitation{
+		WorkspaceID: workspace.ID,
+		Email:       email,
+		Role:        input.Role,
+		TokenHash:   auth.HashToken(token),
+		InvitedByID: user.ID,
+		ExpiresAt:   time.Now().Add(config.Duration("WORKSPACE_INVITATION_TTL", 7*24*time.Hour)),
+	}
+	if err := db.DB.Create(&invitation).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not create invitation")
+		return
+	}
+
+	link := config.FrontendURL() + "/invitations/accept?token=" + url.QueryEscape(token)
+	if err := mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "You are invited to the " + workspace.Name + " workspace on Go Link Vault",
+		Body: user.Email + " invited you to join the " + workspace.Name + " workspace as " + input.Role + ".\n\n" +
+			"Open this link, after creating an account with this address if needed:\n\n" + link,
+	}); err != nil {
+		logger.ErrorLogger.Println("Could not send workspace invitation:", err)
+		ErrorResponse(c, http.StatusInternalServerError, "Could not send invitation email")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, InvitationView{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// GetInvitationsHandler lists the pending invitations of the workspace
+func GetInvitationsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+
+	var invitations []models.WorkspaceInvitation
+	if err := db.DB.Where("workspace_id = ? AND expires_at > ?", workspace.ID, time.Now()).Find(&invitations).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not fetch invitations")
+		return
+	}
+
+	views := make([]InvitationView, 0, len(invitations))
+	for _, invitation := range invitations {
+		views = append(views, InvitationView{ID: invitation.ID, Email: invitation.Email, Role: invitation.Role, ExpiresAt: invitation.ExpiresAt})
+	}
+	SuccessResponse(c, http.StatusOK, views)
+}
+
+func DeleteInvitationHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+
+	result := db.DB.Unscoped().Where("id = ? AND workspace_id = ?", c.Param("invitation_id"), workspace.ID).Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not delete invitation")
+		return
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Invitation deleted"})
+}
+
+// AcceptInvitationHandler adds the current user to the workspace.
+// The invitation must have been sent to the email address of the account.
+func AcceptInvitationHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var invitation models.WorkspaceInvitation
+	if err := db.DB.Where("token_hash = ?", auth.HashToken(input.Token)).First(&invitation).Error; err != nil || time.Now().After(invitation.ExpiresAt) {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired invitation")
+		return
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		ErrorResponse(c, http.StatusForbidden, "This invitation was sent to another email address")
+		return
+	}
+
+	var workspace models.Workspace
+	if err := db.DB.First(&workspace, invitation.WorkspaceID).Error; err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "Invalid or expired invitation")
+		return
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if workspaceRole(user.ID, workspace.ID) == "" {
+			member := models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: invitation.Role}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&invitation).Error
+	}); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not accept invitation")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, WorkspaceView{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		Role:      workspaceRole(user.ID, workspace.ID),
+		CreatedAt: workspace.CreatedAt,
+	})
+}
+
+// workspaceMember loads the member of the URL in the workspace
+func workspaceMember(c *gin.Context, workspace models.Workspace) (models.WorkspaceMember, bool) {
+	var member models.WorkspaceMember
+	if err := db.DB.Where("workspace_id = ? AND user_id = ?", workspace.ID, c.Param("user_id")).First(&member).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, "Member not found")
+		return member, false
+	}
+	return member, true
+}
+
+// SetMemberRoleHandler changes the role of a member, the last owner can't be demoted
+func SetMemberRoleHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := workspaceForRequest(c, user, true)
+	if !ok {
+		return
+	}
+	member, ok := workspaceMember(c, workspace)
+	if !ok {
+		return
+	}
+
+	var input MemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if member.Role == models.WorkspaceOwner && input.Role != models.WorkspaceOwner && countOwners(tx, workspace.ID) <= 1 {
+			return errLastOwner
+		}
+		return tx.Model(&member).Update("role", input.Role).Error
+	})
+	if err == errLastOwner {
+		ErrorResponse(c, http.StatusConflict, "A workspace needs at least one owner")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not update member")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"user_id": member.UserID, "role": input.Role})
+}
+
+// RemoveMemberHandler removes a member. Owners can remove anyone, members can leave.
+// Links added by the member stay in the workspace and go to an owner.
+func RemoveMemberHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workspace, role, ok := workspaceForRequest(c, user, false)
+	if !ok {
+		return
+	}
+	member, ok := workspaceMember(c, workspace)
+	if !ok {
+		return
+	}
+	if member.UserID != user.ID && role != models.WorkspaceOwner {
+		ErrorResponse(c, http.StatusForbidden, "Only owners can manage the workspace")
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if member.Role == models.WorkspaceOwner && countOwners(tx, workspace.ID) <= 1 {
+			return errLastOwner
+		}
+		// Sinon la suppression de son compte emporterait ses liens du workspace
+		if err := handOverLinks(tx, workspace.ID, member.UserID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&member).Error
+	})
+	if err == errLastOwner {
+		ErrorResponse(c, http.StatusConflict, "Transfer ownership or delete the workspace first")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Could not remove member")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"message": "Member removed"})
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+	"github.com/DebroyeAntoine/go_link_vault/internal/mailer"
+	"github.com/DebroyeAntoine/go_link_vault/internal/middleware"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func workspaceRouter() *gin.Engine {
+	r := gin.Default()
+	r.POST("/links", middleware.AuthRequired(), CreateLinkHandler)
+	r.GET("/links", middleware.AuthRequired(), GetLinksHandler)
+	r.PUT("/link/:id", middleware.AuthRequired(), UpdateLinkHandler)
+	r.DELETE("/link/:id", middleware.AuthRequired(), DeleteLinkHandler)
+	r.GET("/link/:id", middleware.AuthRequired(), GetLinkHandler)
+	r.POST("/workspaces", middleware.AuthRequired(), CreateWorkspaceHandler)
+	r.GET("/workspaces", middleware.AuthRequired(), GetWorkspacesHandler)
+	r.GET("/workspaces/:id", middleware.AuthRequired(), GetWorkspaceHandler)
+	r.DELETE("/workspaces/:id", middleware.AuthRequired(), DeleteWorkspaceHandler)
+	r.POST("/workspaces/:id/invitations", middleware.AuthRequired(), InviteMemberHandler)
+	r.PUT("/workspaces/:id/members/:user_id", middleware.AuthRequired(), SetMemberRoleHandler)
+	r.DELETE("/workspaces/:id/members/:user_id", middleware.AuthRequired(), RemoveMemberHandler)
+	r.POST("/invitations/accept", middleware.AuthRequired(), AcceptInvitationHandler)
+	return r
+}
+
+// workspaceUsers crée des utilisateurs et renvoie leurs tokens
+func workspaceUsers(t *testing.T, emails ...string) ([]models.User, []string) {
+	var users []models.User
+	var tokens []string
+	for _, email := range emails {
+		user := models.User{Email: email, Password: "x"}
+		assert.NoError(t, db.DB.Create(&user).Error)
+		token, _ := auth.CreateToken(user)
+		users = append(users, user)
+		tokens = append(tokens, token)
+	}
+	return users, tokens
+}
+
+// joinWorkspace invite l'utilisateur et accepte l'invitation reçue par email
+func joinWorkspace(t *testing.T, router *gin.Engine, rec *recordingMailer, workspaceID uint, ownerToken, email, token, role string) {
+	resp := postJSON(router, fmt.Sprintf("/workspaces/%d/invitations", workspaceID), ownerToken, map[string]string{"email": email, "role": role})
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	invitation := tokenFromMail(t, rec.sent[len(rec.sent)-1])
+
+	resp = postJSON(router, "/invitations/accept", token, map[string]string{"token": invitation})
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestWorkspaceLinksAndRoles(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	_, tokens := workspaceUsers(t, "owner@example.com", "editor@example.com", "viewer@example.com", "outsider@example.com")
+	owner, editor, viewer, outsider := tokens[0], tokens[1], tokens[2], tokens[3]
+
+	router := workspaceRouter()
+
+	resp := postJSON(router, "/workspaces", owner, map[string]string{"name": "Team"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var workspace ResponseData[WorkspaceView]
+	assert.NoError(t, jsonDecode(resp, &workspace))
+	assert.Equal(t, models.WorkspaceOwner, workspace.Data.Role)
+	wsID := workspace.Data.ID
+
+	// L'invitation ne peut être acceptée que par le compte de l'adresse invitée
+	resp = postJSON(router, fmt.Sprintf("/workspaces/%d/invitations", wsID), owner, map[string]string{"email": "editor@example.com", "role": "editor"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	invitation := tokenFromMail(t, rec.sent[0])
+	assert.Equal(t, http.StatusForbidden, postJSON(router, "/invitations/accept", outsider, map[string]string{"token": invitation}).Code)
+	assert.Equal(t, http.StatusOK, postJSON(router, "/invitations/accept", editor, map[string]string{"token": invitation}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJSON(router, "/invitations/accept", editor, map[string]string{"token": invitation}).Code)
+
+	joinWorkspace(t, router, rec, wsID, owner, "viewer@example.com", viewer, "viewer")
+
+	// Seuls les propriétaires invitent
+	assert.Equal(t, http.StatusForbidden, postJSON(router, fmt.Sprintf("/workspaces/%d/invitations", wsID), editor, map[string]string{"email": "x@example.com", "role": "viewer"}).Code)
+
+	scope := fmt.Sprintf("/links?workspace_id=%d", wsID)
+	assert.Equal(t, http.StatusCreated, postJSON(router, scope, editor, map[string]interface{}{"url": "https://go.dev", "title": "Go"}).Code)
+	assert.Equal(t, http.StatusForbidden, postJSON(router, scope, viewer, map[string]interface{}{"url": "https://react.dev", "title": "React"}).Code)
+	assert.Equal(t, http.StatusNotFound, postJSON(router, scope, outsider, map[string]interface{}{"url": "https://react.dev", "title": "React"}).Code)
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", owner, map[string]interface{}{"url": "https://personal.example", "title": "Personal"}).Code)
+
+	var shared models.Link
+	assert.NoError(t, db.DB.Where("url = ?", "https://go.dev").First(&shared).Error)
+	assert.Equal(t, wsID, *shared.WorkspaceID)
+
+	// Les liens du workspace ne se mélangent pas aux liens personnels
+	var links ResponseData[[]models.Link]
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", scope, viewer, nil), &links))
+	assert.Len(t, links.Data, 1)
+	assert.NoError(t, jsonDecode(sendJSON(router, "GET", "/links", owner, nil), &links))
+	assert.Len(t, links.Data, 1)
+	assert.Equal(t, "https://personal.example", links.Data[0].URL)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", scope, outsider, nil).Code)
+
+	path := fmt.Sprintf("/link/%d", shared.ID)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", path, viewer, nil).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "PUT", path, viewer, map[string]string{"title": "Viewer"}).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "DELETE", path, viewer, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, outsider, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "PUT", path, owner, map[string]string{"title": "The Go language"}).Code)
+
+	// Un membre retiré perd l'accès
+	resp = sendJSON(router, "DELETE", fmt.Sprintf("/workspaces/%d/members/%d", wsID, shared.UserID), owner, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", path, editor, nil).Code)
+	assert.Equal(t, http.StatusOK, sendJSON(router, "GET", path, owner, nil).Code)
+}
+
+func TestWorkspaceOwnership(t *testing.T) {
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "member@example.com")
+	router := workspaceRouter()
+
+	resp := postJSON(router, "/workspaces", tokens[0], map[string]string{"name": "Team"})
+	var workspace ResponseData[WorkspaceView]
+	assert.NoError(t, jsonDecode(resp, &workspace))
+	wsID := workspace.Data.ID
+	joinWorkspace(t, router, rec, wsID, tokens[0], "member@example.com", tokens[1], "editor")
+
+	// Le dernier propriétaire ne peut ni partir ni être rétrogradé
+	ownerPath := fmt.Sprintf("/workspaces/%d/members/%d", wsID, users[0].ID)
+	assert.Equal(t, http.StatusConflict, sendJSON(router, "DELETE", ownerPath, tokens[0], nil).Code)
+	assert.Equal(t, http.StatusConflict, sendJSON(router, "PUT", ownerPath, tokens[0], map[string]string{"role": "viewer"}).Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(router, "PUT", ownerPath, tokens[1], map[string]string{"role": "viewer"}).Code)
+
+	assert.Equal(t, http.StatusCreated, postJSON(router, fmt.Sprintf("/links?workspace_id=%d", wsID), tokens[0], map[string]interface{}{"url": "https://go.dev", "title": "Go"}).Code)
+
+	// Le compte du propriétaire est supprimé : le membre hérite du workspace et de ses liens
+	assert.NoError(t, db.DB.Transaction(func(tx *gorm.DB) error { return deleteAccount(tx, users[0]) }))
+	assert.Equal(t, models.WorkspaceOwner, workspaceRole(users[1].ID, wsID))
+	var link models.Link
+	assert.NoError(t, db.DB.Where("workspace_id = ?", wsID).First(&link).Error)
+	assert.Equal(t, users[1].ID, link.UserID)
+
+	// Sans autre membre, la suppression du compte supprime le workspace
+	assert.NoError(t, db.DB.Transaction(func(tx *gorm.DB) error { return deleteAccount(tx, users[1]) }))
+	var count int64
+	db.DB.Unscoped().Model(&models.Link{}).Where("workspace_id = ?", wsID).Count(&count)
+	assert.Zero(t, count)
+	db.DB.Model(&models.Workspace{}).Where("id = ?", wsID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestRemovedMemberLinksStay(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+	rec := &recordingMailer{}
+	mailer.Use(rec)
+	defer mailer.Use(mailer.LogMailer{})
+
+	users, tokens := workspaceUsers(t, "owner@example.com", "member@example.com")
+	router := workspaceRouter()
+
+	resp := postJSON(router, "/workspaces", tokens[0], map[string]string{"name": "Team"})
+	var workspace ResponseData[WorkspaceView]
+	assert.NoError(t, jsonDecode(resp, &workspace))
+	wsID := workspace.Data.ID
+	joinWorkspace(t, router, rec, wsID, tokens[0], "member@example.com", tokens[1], "editor")
+	assert.Equal(t, http.StatusCreated, postJSON(router, fmt.Sprintf("/links?workspace_id=%d", wsID), tokens[1], map[string]interface{}{"url": "https://go.dev", "title": "Go"}).Code)
+
+	// Le membre retiré, ses liens passent au propriétaire
+	assert.Equal(t, http.StatusOK, sendJSON(router, "DELETE", fmt.Sprintf("/workspaces/%d/members/%d", wsID, users[1].ID), tokens[0], nil).Code)
+	var link models.Link
+	assert.NoError(t, db.DB.Where("workspace_id = ?", wsID).First(&link).Error)
+	assert.Equal(t, users[0].ID, link.UserID)
+
+	// Un lien resté à son nom n'est pas supprimé avec son compte
+	left := models.Link{URL: "https://ziglang.org", Title: "Zig", Tags: []byte(`[]`), UserID: users[1].ID, WorkspaceID: &wsID}
+	assert.NoError(t, db.DB.Create(&left).Error)
+	assert.NoError(t, db.DB.Transaction(func(tx *gorm.DB) error { return deleteAccount(tx, users[1]) }))
+	assert.NoError(t, db.DB.First(&left, left.ID).Error)
+	assert.Equal(t, users[0].ID, left.UserID)
+	var count int64
+	db.DB.Model(&models.Link{}).Where("workspace_id = ?", wsID).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestIDParamIsNotSQL(t *testing.T) {
+	logger.InitLogger()
+	db.SetupTestDB()
+
+	_, tokens := workspaceUsers(t, "owner@example.com")
+	router := workspaceRouter()
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/links", tokens[0], map[string]interface{}{"url": "https://go.dev", "title": "Go"}).Code)
+	assert.Equal(t, http.StatusCreated, postJSON(router, "/workspaces", tokens[0], map[string]string{"name": "Team"}).Code)
+
+	// Un :id qui n'est pas un nombre ne doit jamais devenir une condition SQL
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", "/link/0%20OR%201=1", tokens[0], nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, "GET", "/workspaces/0%20OR%201=1", tokens[0], nil).Code)
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrMissingColumn is returned when a CSV export has no url column
+var ErrMissingColumn = errors.New("the CSV file has no url column")
+
+// csvRecords reads a CSV file with a header line and returns its rows as maps
+// from the lower-cased column names to the values
+func csvRecords(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+
+	var records []map[string]string
+	hasURL := false
+	for _, column := range header {
+		hasURL = hasURL || column == "url"
+	}
+	if !hasURL {
+		return nil, ErrMissingColumn
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := map[string]string{}
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// linkdingBookmark is a bookmark of the Linkding REST API (/api/bookmarks/)
+type linkdingBookmark struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Notes       string   `json:"notes"`
+	TagNames    []string `json:"tag_names"`
+	Unread      bool     `json:"unread"`
+	DateAdded   string   `json:"date_added"`
+}
+
+// ParseLinkding reads the bookmarks of Linkding as returned by its API, either a page
+// ({"results": [...]}) or the list of bookmarks
+func ParseLinkding(r io.Reader) ([]Bookmark, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []linkdingBookmark
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		var page struct {
+			Results []linkdingBookmark `json:"results"`
+		}
+		err = json.Unmarshal(trimmed, &page)
+		items = page.Results
+	} else {
+		err = json.Unmarshal(raw, &items)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := make([]Bookmark, 0, len(items))
+	for _, item := range items {
+		bookmarks = append(bookmarks, Bookmark{
+			URL:         strings.TrimSpace(item.URL),
+			Title:       item.Title,
+			Description: item.Description,
+			Notes:       item.Notes,
+			Tags:        item.TagNames,
+			AddedAt:     isoTime(item.DateAdded),
+			Read:        !item.Unread,
+		})
+	}
+	return bookmarks, nil
+}
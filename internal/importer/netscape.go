@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Bookmark is a link read from a bookmarks export
+type Bookmark struct {
+	URL         string
+	Title       string
+	Description string
+	Notes       string
+	Tags        []string
+	// Dossiers du favori, du plus haut au plus profond
+	Folders []string
+	AddedAt time.Time
+	Read    bool
+}
+
+// Les dossiers racine des navigateurs ("Barre de favoris"...) ne sont pas des dossiers de l'utilisateur
+var rootFolderAttrs = []string{"personal_toolbar_folder", "unfiled_bookmarks_folder"}
+
+// ParseNetscape reads the bookmarks.html file exported by Chrome, Firefox, Safari or Edge.
+// Folders are <H3> headings followed by a <DL> list, bookmarks are <A> links, optionally
+// followed by a <DD> description.
+func ParseNetscape(r io.Reader) ([]Bookmark, error) {
+	var (
+		bookmarks []Bookmark
+		folders   []string
+		// Pile des <DL> ouverts : true si le <DL> ouvre un dossier compté dans folders
+		lists   []bool
+		pending *string // dossier dont on attend le <DL>
+
+		current   *Bookmark
+		inTitle   bool
+		inFolder  bool
+		rootTitle bool
+		inDesc    bool
+		text      strings.Builder
+	)
+
+	flushDesc := func() {
+		if inDesc && len(bookmarks) > 0 {
+			bookmarks[len(bookmarks)-1].Description = strings.TrimSpace(text.String())
+		}
+		inDesc = false
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				flushDesc()
+				return bookmarks, nil
+			}
+			return nil, z.Err()
+
+		case html.TextToken:
+			if inTitle || inFolder || inDesc {
+				text.Write(z.Text())
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			if tag != atom.P {
+				flushDesc()
+			}
+
+			switch tag {
+			case atom.H3:
+				inFolder = true
+				rootTitle = false
+				text.Reset()
+				for hasAttr {
+					var key []byte
+					key, _, hasAttr = z.TagAttr()
+					for _, root := range rootFolderAttrs {
+						if string(key) == root {
+							rootTitle = true
+						}
+					}
+				}
+			case atom.Dl:
+				if pending != nil {
+					folders = append(folders, *pending)
+					lists = append(lists, true)
+					pending = nil
+				} else {
+					lists = append(lists, false)
+				}
+			case atom.A:
+				current = &Bookmark{Folders: append([]string(nil), folders...)}
+				for hasAttr {
+					var key, value []byte
+					key, value, hasAttr = z.TagAttr()
+					switch string(key) {
+					case "href":
+						current.URL = strings.TrimSpace(string(value))
+					case "add_date":
+						current.AddedAt = unixTime(string(value))
+					case "tags":
+						current.Tags = splitTags(string(value))
+					}
+				}
+				inTitle = true
+				text.Reset()
+			case atom.Dd:
+				if len(bookmarks) > 0 {
+					inDesc = true
+					text.Reset()
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.H3:
+				if inFolder {
+					title := strings.TrimSpace(text.String())
+					pending = &title
+					if rootTitle {
+						// Son <DL> est ouvert sans ajouter de dossier
+						pending = nil
+					}
+				}
+				inFolder = false
+			case atom.A:
+				if current != nil {
+					current.Title = strings.TrimSpace(text.String())
+					bookmarks = append(bookmarks, *current)
+					current = nil
+				}
+				inTitle = false
+			case atom.Dl:
+				flushDesc()
+				if len(lists) > 0 {
+					if lists[len(lists)-1] {
+						folders = folders[:len(folders)-1]
+					}
+					lists = lists[:len(lists)-1]
+				}
+			}
+		}
+	}
+}
+
+// splitTags splits comma separated tags
+func splitTags(value string) []string {
+	return splitTagsOn(value, ",")
+}
+
+// splitTagsOn splits tags on the separator, ignoring empty ones
+func splitTagsOn(value, separator string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, separator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const firefoxExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file. -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks Menu</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1600000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks Toolbar</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1600000100" TAGS="go,lang">The Go Programming Language</A>
+        <DD>Build simple, secure, scalable systems
+        <DT><H3>Dev</H3>
+        <DL><p>
+            <DT><H3>Rust &amp; friends</H3>
+            <DL><p>
+                <DT><A HREF="https://www.rust-lang.org/">Rust</A>
+            </DL><p>
+            <DT><A HREF="https://ziglang.org/" ADD_DATE="1600000200">Zig</A>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="place:sort=8&maxResults=10">Recent Tags</A>
+</DL>
+`
+
+func TestParseNetscape(t *testing.T) {
+	bookmarks, err := ParseNetscape(strings.NewReader(firefoxExport))
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 4)
+
+	// Le dossier de la barre d'outils n'est pas un dossier de l'utilisateur
+	assert.Equal(t, "https://go.dev/", bookmarks[0].URL)
+	assert.Equal(t, "The Go Programming Language", bookmarks[0].Title)
+	assert.Equal(t, []string{"go", "lang"}, bookmarks[0].Tags)
+	assert.Empty(t, bookmarks[0].Folders)
+	assert.Equal(t, time.Unix(1600000100, 0), bookmarks[0].AddedAt)
+	assert.Equal(t, "Build simple, secure, scalable systems", bookmarks[0].Description)
+
+	assert.Equal(t, "Rust", bookmarks[1].Title)
+	assert.Equal(t, []string{"Dev", "Rust & friends"}, bookmarks[1].Folders)
+	assert.True(t, bookmarks[1].AddedAt.IsZero())
+	assert.Empty(t, bookmarks[1].Description)
+
+	assert.Equal(t, []string{"Dev"}, bookmarks[2].Folders)
+	assert.Empty(t, bookmarks[3].Folders)
+	assert.Equal(t, "place:sort=8&maxResults=10", bookmarks[3].URL)
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// pinboardPost is a bookmark of the Pinboard JSON export (posts/all)
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"` // le titre
+	Extended    string `json:"extended"`    // les notes
+	Tags        string `json:"tags"`        // séparés par des espaces
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+}
+
+// ParsePinboard reads the JSON export of Pinboard
+func ParsePinboard(r io.Reader) ([]Bookmark, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, err
+	}
+
+	bookmarks := make([]Bookmark, 0, len(posts))
+	for _, post := range posts {
+		bookmarks = append(bookmarks, Bookmark{
+			URL:     strings.TrimSpace(post.Href),
+			Title:   post.Description,
+			Notes:   post.Extended,
+			Tags:    strings.Fields(post.Tags),
+			AddedAt: isoTime(post.Time),
+			Read:    post.ToRead != "yes",
+		})
+	}
+	return bookmarks, nil
+}
+
+// isoTime parses an RFC 3339 date, the zero time if it is not one
+func isoTime(value string) time.Time {
+	at, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return at
+}
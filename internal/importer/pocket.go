@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ParsePocket reads a Pocket export, either the old ril_export.html file or the
+// part_000000.csv file of the current export
+func ParsePocket(r io.Reader) ([]Bookmark, error) {
+	reader := bufio.NewReader(r)
+	start, _ := reader.Peek(512)
+	if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(string(start), "\ufeff")), "<") {
+		return parsePocketHTML(reader)
+	}
+	return parsePocketCSV(reader)
+}
+
+// parsePocketHTML reads the <h1>Unread</h1> and <h1>Read Archive</h1> lists of links
+func parsePocketHTML(r io.Reader) ([]Bookmark, error) {
+	var (
+		bookmarks []Bookmark
+		current   *Bookmark
+		inHeading bool
+		archive   bool
+		text      strings.Builder
+	)
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return bookmarks, nil
+			}
+			return nil, z.Err()
+
+		case html.TextToken:
+			if inHeading || current != nil {
+				text.Write(z.Text())
+			}
+
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.H1:
+				inHeading = true
+				text.Reset()
+			case atom.A:
+				current = &Bookmark{Read: archive}
+				for hasAttr {
+					var key, value []byte
+					key, value, hasAttr = z.TagAttr()
+					switch string(key) {
+					case "href":
+						current.URL = strings.TrimSpace(string(value))
+					case "time_added":
+						current.AddedAt = unixTime(string(value))
+					case "tags":
+						current.Tags = splitTags(string(value))
+					}
+				}
+				text.Reset()
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.H1:
+				archive = strings.Contains(strings.ToLower(text.String()), "archive")
+				inHeading = false
+			case atom.A:
+				if current != nil {
+					current.Title = strings.TrimSpace(text.String())
+					bookmarks = append(bookmarks, *current)
+					current = nil
+				}
+			}
+		}
+	}
+}
+
+// parsePocketCSV reads the title,url,time_added,tags,status columns, tags are separated by |
+func parsePocketCSV(r io.Reader) ([]Bookmark, error) {
+	records, err := csvRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := make([]Bookmark, 0, len(records))
+	for _, record := range records {
+		bookmarks = append(bookmarks, Bookmark{
+			URL:     record["url"],
+			Title:   record["title"],
+			Tags:    splitTagsOn(record["tags"], "|"),
+			AddedAt: unixTime(record["time_added"]),
+			Read:    record["status"] == "archive",
+		})
+	}
+	return bookmarks, nil
+}
+
+// unixTime parses a date in Unix seconds, the zero time if it is not one
+func unixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"io"
+	"strings"
+)
+
+// ParseRaindrop reads the CSV export of Raindrop.io. Its folder column is "Folder" or
+// "Parent/Folder" for nested collections, notes are in note and the page summary in excerpt.
+// Raindrop has no read status.
+func ParseRaindrop(r io.Reader) ([]Bookmark, error) {
+	records, err := csvRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := make([]Bookmark, 0, len(records))
+	for _, record := range records {
+		var folders []string
+		for _, folder := range strings.Split(record["folder"], "/") {
+			if folder = strings.TrimSpace(folder); folder != "" && !strings.EqualFold(folder, "Unsorted") {
+				folders = append(folders, folder)
+			}
+		}
+
+		bookmarks = append(bookmarks, Bookmark{
+			URL:         record["url"],
+			Title:       record["title"],
+			Description: record["excerpt"],
+			Notes:       record["note"],
+			Tags:        splitTags(record["tags"]),
+			Folders:     folders,
+			AddedAt:     isoTime(record["created"]),
+		})
+	}
+	return bookmarks, nil
+}
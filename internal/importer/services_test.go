@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePocket(t *testing.T) {
+	bookmarks, err := ParsePocket(strings.NewReader(`<!DOCTYPE html>
+<html><body>
+<h1>Unread</h1>
+<ul><li><a href="https://go.dev/" time_added="1600000000" tags="go,lang">Go</a></li></ul>
+<h1>Read Archive</h1>
+<ul><li><a href="https://ziglang.org/" time_added="1600000100" tags="">Zig</a></li></ul>
+</body></html>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Bookmark{
+		{URL: "https://go.dev/", Title: "Go", Tags: []string{"go", "lang"}, AddedAt: time.Unix(1600000000, 0)},
+		{URL: "https://ziglang.org/", Title: "Zig", AddedAt: time.Unix(1600000100, 0), Read: true},
+	}, bookmarks)
+
+	bookmarks, err = ParsePocket(strings.NewReader("title,url,time_added,tags,status\n" +
+		"Go,https://go.dev/,1600000000,go|lang,unread\n" +
+		"\"Zig, fast\",https://ziglang.org/,1600000100,,archive\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Bookmark{
+		{URL: "https://go.dev/", Title: "Go", Tags: []string{"go", "lang"}, AddedAt: time.Unix(1600000000, 0)},
+		{URL: "https://ziglang.org/", Title: "Zig, fast", AddedAt: time.Unix(1600000100, 0), Read: true},
+	}, bookmarks)
+
+	_, err = ParsePocket(strings.NewReader("title,link\nGo,https://go.dev/\n"))
+	assert.ErrorIs(t, err, ErrMissingColumn)
+}
+
+func TestParsePinboard(t *testing.T) {
+	bookmarks, err := ParsePinboard(strings.NewReader(`[
+		{"href":"https://go.dev/","description":"Go","extended":"Read the tour","tags":"go lang","time":"2020-09-13T12:26:40Z","toread":"yes"},
+		{"href":"https://ziglang.org/","description":"Zig","extended":"","tags":"","time":"2020-09-13T12:28:20Z","toread":"no"}
+	]`))
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+	assert.Equal(t, "Read the tour", bookmarks[0].Notes)
+	assert.Equal(t, []string{"go", "lang"}, bookmarks[0].Tags)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), bookmarks[0].AddedAt.UTC())
+	assert.False(t, bookmarks[0].Read)
+	assert.True(t, bookmarks[1].Read)
+}
+
+func TestParseRaindrop(t *testing.T) {
+	bookmarks, err := ParseRaindrop(strings.NewReader("id,title,note,excerpt,url,folder,tags,created,cover,highlights,favorite\n" +
+		"1,Go,My note,The Go language,https://go.dev/,Dev/Languages,\"go, lang\",2020-09-13T12:26:40.000Z,,,false\n" +
+		"2,Zig,,,https://ziglang.org/,Unsorted,,2020-09-13T12:28:20.000Z,,,false\n"))
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+	assert.Equal(t, Bookmark{
+		URL: "https://go.dev/", Title: "Go", Description: "The Go language", Notes: "My note",
+		Tags: []string{"go", "lang"}, Folders: []string{"Dev", "Languages"}, AddedAt: time.Unix(1600000000, 0).UTC(),
+	}, bookmarks[0])
+	assert.Empty(t, bookmarks[1].Folders)
+}
+
+func TestParseLinkding(t *testing.T) {
+	page := `{"count":1,"next":null,"results":[
+		{"url":"https://go.dev/","title":"Go","description":"The Go language","notes":"Read the tour",
+		 "tag_names":["go"],"unread":false,"date_added":"2020-09-13T12:26:40.123456Z"}
+	]}`
+	bookmarks, err := ParseLinkding(strings.NewReader(page))
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 1)
+	assert.Equal(t, "Read the tour", bookmarks[0].Notes)
+	assert.Equal(t, []string{"go"}, bookmarks[0].Tags)
+	assert.True(t, bookmarks[0].Read)
+	assert.Equal(t, int64(1600000000), bookmarks[0].AddedAt.Unix())
+
+	bookmarks, err = ParseLinkding(strings.NewReader(`[{"url":"https://ziglang.org/","title":"Zig","unread":true}]`))
+	assert.NoError(t, err)
+	assert.False(t, bookmarks[0].Read)
+}
@@ -0,0 +1,119 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Key is a public JSON Web Key (RFC 7517)
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC et OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Set is the document served on a jwks_uri
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// Find returns the key with the given kid
+func (s Set) Find(kid string) (Key, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+var b64 = base64.RawURLEncoding
+
+// PublicKey converts the JWK to an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// FromPublicKey builds the JWK of a public key
+func FromPublicKey(pub crypto.PublicKey, kid, alg string) (Key, error) {
+	key := Key{Kid: kid, Alg: alg, Use: "sig"}
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = b64.EncodeToString(p.N.Bytes())
+		key.E = b64.EncodeToString(big.NewInt(int64(p.E)).Bytes())
+	case *ecdsa.PublicKey:
+		key.Kty = "EC"
+		key.Crv = p.Curve.Params().Name
+		size := (p.Curve.Params().BitSize + 7) / 8
+		key.X = b64.EncodeToString(p.X.FillBytes(make([]byte, size)))
+		key.Y = b64.EncodeToString(p.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = b64.EncodeToString(p)
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return key, nil
+}
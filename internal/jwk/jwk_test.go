@@ -0,0 +1,44 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, pub := range []interface{}{&rsaKey.PublicKey, &ecKey.PublicKey, edPub} {
+		key, err := FromPublicKey(pub, "kid-1", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "sig", key.Use)
+
+		parsed, err := key.PublicKey()
+		assert.NoError(t, err)
+		assert.Equal(t, pub, parsed)
+	}
+}
+
+func TestSetFind(t *testing.T) {
+	set := Set{Keys: []Key{{Kty: "RSA", Kid: "a"}, {Kty: "EC", Kid: "b"}}}
+
+	key, ok := set.Find("b")
+	assert.True(t, ok)
+	assert.Equal(t, "EC", key.Kty)
+
+	_, ok = set.Find("c")
+	assert.False(t, ok)
+}
+
+func TestUnsupportedKey(t *testing.T) {
+	_, err := Key{Kty: "oct"}.PublicKey()
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/logger"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. Any implementation can be plugged in with Use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var (
+	mu      sync.RWMutex
+	current Mailer = LogMailer{}
+)
+
+// Use replaces the mailer used by Send
+func Use(m Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = m
+}
+
+// Send delivers the message with the current mailer
+func Send(msg Message) error {
+	mu.RLock()
+	m := current
+	mu.RUnlock()
+	return m.Send(msg)
+}
+
+// FromEnv builds the mailer selected by MAIL_DRIVER ("log" by default, or "smtp")
+func FromEnv() Mailer {
+	switch strings.ToLower(config.String("MAIL_DRIVER", "log")) {
+	case "smtp":
+		return SMTPMailer{
+			Host:     config.String("SMTP_HOST", "localhost"),
+			Port:     config.String("SMTP_PORT", "25"),
+			Username: config.String("SMTP_USERNAME", ""),
+			Password: config.String("SMTP_PASSWORD", ""),
+			From:     config.String("MAIL_FROM", "no-reply@localhost"),
+		}
+	default:
+		return LogMailer{}
+	}
+}
+
+// LogMailer only writes emails to the logs, handy in development
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	out := log.Default()
+	if logger.InfoLogger != nil {
+		out = logger.InfoLogger
+	}
+	out.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPMailer delivers emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(msg Message) error {
+	var a smtp.Auth
+	if m.Username != "" {
+		a = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, a, m.From, []string{msg.To}, m.build(msg))
+}
+
+func (m SMTPMailer) build(msg Message) []byte {
+	// Évite l'injection d'en-têtes via le sujet ou le destinataire
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		clean.Replace(m.From), clean.Replace(msg.To), clean.Replace(msg.Subject), msg.Body))
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMailer struct {
+	sent []Message
+}
+
+func (r *recordingMailer) Send(msg Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestUseReplacesMailer(t *testing.T) {
+	rec := &recordingMailer{}
+	Use(rec)
+	defer Use(LogMailer{})
+
+	err := Send(Message{To: "a@example.com", Subject: "Hello", Body: "World"})
+	assert.NoError(t, err)
+	assert.Len(t, rec.sent, 1)
+	assert.Equal(t, "a@example.com", rec.sent[0].To)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("MAIL_DRIVER", "")
+	assert.IsType(t, LogMailer{}, FromEnv())
+
+	t.Setenv("MAIL_DRIVER", "smtp")
+	t.Setenv("SMTP_HOST", "mail.example.com")
+	m, ok := FromEnv().(SMTPMailer)
+	assert.True(t, ok)
+	assert.Equal(t, "mail.example.com", m.Host)
+}
+
+func TestSMTPMessageStripsHeaderInjection(t *testing.T) {
+	m := SMTPMailer{From: "vault@example.com"}
+	raw := string(m.build(Message{To: "a@example.com", Subject: "Hi\r\nBcc: evil@example.com", Body: "body"}))
+
+	assert.Contains(t, raw, "Subject: HiBcc: evil@example.com\r\n")
+	assert.False(t, strings.Contains(raw, "\r\nBcc:"))
+}
@@ -59,3 +59,35 @@ func TestAuthRequired(t *testing.T) {
 		assert.JSONEq(t, `{"message": "You are authorized!"}`, resp.Body.String())
 	})
 }
+
+func TestAuthRequiredWithSessionCookie(t *testing.T) {
+	router := gin.Default()
+	router.GET("/protected", AuthRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"cookie": CookieSession(c)})
+	})
+	router.POST("/protected", AuthRequired(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	token, _ := auth.CreateToken(models.User{Email: "test@example.com"})
+	send := func(method, csrf string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/protected", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+		req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf-value"})
+		if csrf != "" {
+			req.Header.Set(CSRFHeader, csrf)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send("GET", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"cookie": true}`, resp.Body.String())
+
+	// Les méthodes qui modifient l'état exigent le double envoi du token CSRF
+	assert.Equal(t, http.StatusForbidden, send("POST", "").Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", "other-value").Code)
+	assert.Equal(t, http.StatusNoContent, send("POST", "csrf-value").Code)
+}
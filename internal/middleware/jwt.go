@@ -2,40 +2,48 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
+// AuthRequired accepts the token from the Authorization header or from the session cookie.
+// With the cookie, state-changing requests must also send the CSRF token in X-CSRF-Token.
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		if strings.HasPrefix(authHeader, "Bearer ") {
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+		} else if cookie, err := c.Cookie(SessionCookie); authHeader == "" && err == nil && cookie != "" {
+			// Le navigateur envoie le cookie tout seul : on exige le token CSRF
+			if !safeMethod(c.Request.Method) && !validCSRF(c) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+				return
+			}
+			tokenString = cookie
+			c.Set(cookieSessionKey, true)
+		} else {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-		// Parse avec claims
-		token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return auth.JwtKey(), nil
-		})
-
-		if err != nil || !token.Valid {
+		// Parse avec claims, la clé est choisie selon le kid du token
+		claims, err := auth.ParseToken(tokenString)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
 
 		// Set dans le contexte
-		if claims, ok := token.Claims.(*jwt.StandardClaims); ok {
-			c.Set("userEmail", claims.Issuer)
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			return
+		c.Set("userEmail", claims.Issuer)
+		// Les anciens tokens n'ont pas de subject : on se rabat alors sur l'email
+		if id, err := strconv.ParseUint(claims.Subject, 10, 64); err == nil && id > 0 {
+			c.Set("userID", uint(id))
 		}
+		c.Set("sessionVersion", claims.SessionVersion)
 
 		c.Next()
 	}
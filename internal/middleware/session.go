@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SessionCookie holds the session token, HttpOnly so it can't be read by scripts
+	SessionCookie = "session"
+	// CSRFCookie holds the CSRF token, readable by the front-end which copies it in CSRFHeader
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// cookieSessionKey marks in the context a request authenticated by the session cookie
+const cookieSessionKey = "cookieSession"
+
+// CookieSession reports whether the request was authenticated by the session cookie
+// instead of the Authorization header
+func CookieSession(c *gin.Context) bool {
+	return c.GetBool(cookieSessionKey)
+}
+
+// sameSite parses SESSION_COOKIE_SAMESITE (lax, strict or none)
+func sameSite() http.SameSite {
+	switch strings.ToLower(config.String("SESSION_COOKIE_SAMESITE", "lax")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+func setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	c.SetSameSite(sameSite())
+	c.SetCookie(name, value, maxAge, "/", config.String("SESSION_COOKIE_DOMAIN", ""),
+		config.Bool("SESSION_COOKIE_SECURE", true), httpOnly)
+}
+
+// SetSessionCookies stores the token in the session cookie and returns the new CSRF token,
+// also sent in its own cookie for the double-submit check
+func SetSessionCookies(c *gin.Context, token string) (string, error) {
+	csrf, err := auth.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	maxAge := int(auth.TokenLifetime.Seconds())
+	setCookie(c, SessionCookie, token, maxAge, true)
+	setCookie(c, CSRFCookie, csrf, maxAge, false)
+	return csrf, nil
+}
+
+// ClearSessionCookies expires both session cookies
+func ClearSessionCookies(c *gin.Context) {
+	setCookie(c, SessionCookie, "", -1, true)
+	setCookie(c, CSRFCookie, "", -1, false)
+}
+
+// safeMethod reports whether the method can't change state and needs no CSRF token
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// validCSRF compares the CSRF header to the CSRF cookie (double-submit)
+func validCSRF(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie)) == 1
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrSessionRevoked  = errors.New("session revoked")
+	ErrAccountDisabled = errors.New("account disabled")
+)
+
+// CurrentUser loads the user authenticated by AuthRequired, once per request.
+// Tokens issued before the user revoked their sessions, or of disabled accounts, are refused.
+func CurrentUser(c *gin.Context) (models.User, error) {
+	if cached, ok := c.Get("user"); ok {
+		return cached.(models.User), nil
+	}
+
+	var user models.User
+	query := db.DB
+	if id, ok := c.Get("userID"); ok {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("email = ?", c.GetString("userEmail"))
+	}
+	if err := query.First(&user).Error; err != nil {
+		return user, ErrUserNotFound
+	}
+
+	if c.GetInt("sessionVersion") != user.SessionVersion {
+		return user, ErrSessionRevoked
+	}
+	if user.IsDisabled() {
+		return user, ErrAccountDisabled
+	}
+
+	c.Set("user", user)
+	return user, nil
+}
+
+// abortWithUserError answers the error returned by CurrentUser
+func abortWithUserError(c *gin.Context, err error) {
+	status := http.StatusUnauthorized
+	if err == ErrAccountDisabled {
+		status = http.StatusForbidden
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+}
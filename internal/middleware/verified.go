@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedRequired blocks users who did not confirm their email address.
+// It only applies when REQUIRE_EMAIL_VERIFICATION is enabled and must run after AuthRequired.
+func VerifiedRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.Bool("REQUIRE_EMAIL_VERIFICATION", false) {
+			c.Next()
+			return
+		}
+
+		user, err := CurrentUser(c)
+		if err != nil {
+			abortWithUserError(c, err)
+			return
+		}
+
+		if !user.IsVerified() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Email address not verified"})
+			return
+		}
+
+		c.Next()
+	}
+}
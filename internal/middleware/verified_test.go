@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/auth"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiedRequired(t *testing.T) {
+	db.SetupTestDB()
+
+	now := time.Now()
+	unverified := models.User{Email: "unverified@example.com", Password: "x"}
+	verified := models.User{Email: "verified@example.com", Password: "x", EmailVerifiedAt: &now}
+	assert.NoError(t, db.DB.Create(&unverified).Error)
+	assert.NoError(t, db.DB.Create(&verified).Error)
+
+	router := gin.Default()
+	router.GET("/restricted", AuthRequired(), VerifiedRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	call := func(user models.User) int {
+		token, _ := auth.CreateToken(user)
+		req, _ := http.NewRequest("GET", "/restricted", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	t.Run("Restriction disabled", func(t *testing.T) {
+		t.Setenv("REQUIRE_EMAIL_VERIFICATION", "false")
+		assert.Equal(t, http.StatusOK, call(unverified))
+	})
+
+	t.Run("Restriction enabled", func(t *testing.T) {
+		t.Setenv("REQUIRE_EMAIL_VERIFICATION", "true")
+		assert.Equal(t, http.StatusForbidden, call(unverified))
+		assert.Equal(t, http.StatusOK, call(verified))
+	})
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrAppendOnly is returned when trying to modify or delete an audit event
+var ErrAppendOnly = errors.New("audit events are append-only")
+
+// AuditEvent is an append-only record of a security relevant action or of a change
+// of a link or a collection. Before and After only hold the fields that changed.
+type AuditEvent struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `gorm:"index" json:"created_at"`
+	ActorID    *uint          `gorm:"index" json:"actor_id,omitempty"`
+	Action     string         `gorm:"index" json:"action"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   string         `json:"target_id,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	Details    datatypes.JSON `json:"details,omitempty"`
+	Before     datatypes.JSON `gorm:"column:old_values" json:"before,omitempty"`
+	After      datatypes.JSON `gorm:"column:new_values" json:"after,omitempty"`
+	// Qui voit l'événement dans son fil d'activité : le propriétaire d'un élément personnel
+	// ou les membres du workspace
+	OwnerID     *uint `gorm:"index" json:"-"`
+	WorkspaceID *uint `gorm:"index" json:"workspace_id,omitempty"`
+}
+
+// BeforeUpdate refuses any modification of a recorded event
+func (AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
+
+// BeforeDelete refuses the deletion of a recorded event
+func (AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Collection groups links of a user, a link belongs to at most one collection
+type Collection struct {
+	gorm.Model
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	UserID      uint   `json:"-" gorm:"index"`
+}
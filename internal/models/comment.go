@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Comment is a message on a link. Replies point to their parent comment.
+type Comment struct {
+	gorm.Model
+	LinkID   uint  `gorm:"index"`
+	UserID   uint  `gorm:"index"`
+	ParentID *uint `gorm:"index"`
+	Body     string
+	EditedAt *time.Time
+}
+
+// CommentMention records a user mentioned in a comment with @email
+type CommentMention struct {
+	ID        uint `gorm:"primarykey"`
+	CommentID uint `gorm:"index"`
+	UserID    uint `gorm:"index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// FeedToken authenticates the feed URLs of a user: feed readers can't send a bearer token,
+// so the secret is part of the URL. Only its hash is stored, the URL is shown once at creation.
+type FeedToken struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	Name      string // lecteur de flux qui l'utilise, pour s'y retrouver
+}
@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// Permissions given by a grant
+const (
+	PermissionView = "view"
+	PermissionEdit = "edit" // modifier et supprimer les liens
+)
+
+// Grant gives another user access to a personal link or to all the links of a collection
+type Grant struct {
+	gorm.Model
+	OwnerID      uint  `gorm:"index"`
+	UserID       uint  `gorm:"index"` // bénéficiaire
+	LinkID       *uint `gorm:"index"`
+	CollectionID *uint `gorm:"index"`
+	Permission   string
+}
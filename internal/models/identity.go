@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExternalIdentity links an account of an identity provider (OIDC) to a user
+type ExternalIdentity struct {
+	gorm.Model
+	UserID  uint   `gorm:"index"`
+	Issuer  string `gorm:"uniqueIndex:idx_identity_issuer_subject"`
+	Subject string `gorm:"uniqueIndex:idx_identity_issuer_subject"`
+	Email   string
+}
+
+// OIDCLoginState keeps the PKCE verifier and nonce between the redirect and the callback
+type OIDCLoginState struct {
+	gorm.Model
+	StateHash string `gorm:"uniqueIndex"`
+	Verifier  string
+	Nonce     string
+	ExpiresAt time.Time
+	// La session sera ouverte par cookie plutôt que par token
+	CookieSession bool
+}
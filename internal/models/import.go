@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// States of an import job
+const (
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// ImportJob follows an import of links running in the background
+type ImportJob struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uint      `gorm:"index" json:"-"`
+	WorkspaceID *uint     `gorm:"index" json:"workspace_id,omitempty"`
+	Format      string    `json:"format"`
+	Status      string    `json:"status"`
+	Total       int       `json:"total"`
+	Processed   int       `json:"processed"`
+	Created     int       `json:"created"`
+	Skipped     int       `json:"skipped"`
+	Failed      int       `json:"failed"`
+	// Favoris non importés, avec la raison
+	Failures datatypes.JSON `json:"failures,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/urlnorm"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -14,8 +17,31 @@ type Link struct {
 	URL         string         `json:"url" binding:"required,url"`
 	Title       string         `json:"title" binding:"required"`
 	Tags        datatypes.JSON `json:"tags"`
-	UserID      uint           `json:"-"` // Clé étrangère
+	UserID      uint           `json:"-"` // Clé étrangère, le créateur pour un lien de workspace
 	User        User           `gorm:"foreignKey:UserID" json:"-"`
 	Description string         `json:"description,omitempty"`
 	Image       string         `json:"image,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	// Date de lecture, nil si le lien n'a pas encore été lu
+	ReadAt *time.Time `json:"read_at,omitempty"`
+	// Date d'archivage : le lien est gardé mais n'apparaît plus dans la liste par défaut
+	ArchivedAt *time.Time `json:"archived_at,omitempty" gorm:"index"`
+	// Collection optionnelle, remise à nil si la collection est supprimée
+	CollectionID *uint `json:"collection_id,omitempty" gorm:"index"`
+	// Workspace du lien, nil pour un lien personnel
+	WorkspaceID *uint `json:"workspace_id,omitempty" gorm:"index"`
+	// Clés de détection des doublons : l'URL normalisée, et l'URL canonique annoncée par la
+	// page (<link rel="canonical">) une fois le lien scrapé
+	NormalizedURL string `json:"-" gorm:"index"`
+	CanonicalURL  string `json:"-" gorm:"index"`
+	// Calculé à la lecture, pas stocké
+	CommentCount int64 `json:"comment_count" gorm:"-"`
+}
+
+// BeforeSave keeps the normalized URL in sync with the URL
+func (l *Link) BeforeSave(tx *gorm.DB) error {
+	if l.URL != "" {
+		l.NormalizedURL = urlnorm.Normalize(l.URL)
+	}
+	return nil
 }
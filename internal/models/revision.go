@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// LinkRevision is a version of the URL, title and tags of a link, saved at each change
+type LinkRevision struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	LinkID    uint      `gorm:"index"`
+	// Auteur de la version, nil pour l'état d'origine d'un lien créé avant l'historique
+	EditorID *uint `gorm:"index"`
+	URL      string
+	Title    string
+	Tags     datatypes.JSON
+	// Version restaurée, si la version est issue d'une restauration
+	RestoredFromID *uint
+}
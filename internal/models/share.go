@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of share links
+const (
+	ShareLink       = "link"
+	ShareTag        = "tag"
+	ShareCollection = "collection"
+)
+
+// Share is a public read-only URL to a link, to the links of a tag or to a collection.
+// Only the hash of the token is stored, the URL is shown once at creation.
+type Share struct {
+	gorm.Model
+	UserID       uint   `gorm:"index"`
+	TokenHash    string `gorm:"uniqueIndex"`
+	Kind         string
+	LinkID       *uint
+	Tag          string
+	CollectionID *uint
+	ExpiresAt    *time.Time
+	PasswordHash string // vide si le partage n'est pas protégé
+}
+
+// IsExpired reports whether the share can no longer be opened
+func (s Share) IsExpired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
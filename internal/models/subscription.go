@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// States of a subscription, after its last poll
+const (
+	SubscriptionPending = "pending" // pas encore relevé
+	SubscriptionOK      = "ok"
+	SubscriptionError   = "error"
+)
+
+// Subscription follows an RSS, Atom or JSON Feed source: the new entries are saved as links,
+// personal or in the workspace, with the tags of the subscription
+type Subscription struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UserID      uint           `gorm:"index" json:"-"`
+	WorkspaceID *uint          `gorm:"index" json:"workspace_id,omitempty"`
+	URL         string         `json:"url"`
+	Title       string         `json:"title"`
+	Tags        datatypes.JSON `json:"tags"`
+
+	// Validateurs HTTP du dernier relevé, renvoyés pour obtenir un 304
+	ETag         string `json:"-"`
+	LastModified string `json:"-"`
+
+	Status        string     `json:"status"`
+	LastPolledAt  *time.Time `json:"last_polled_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	Saved         int        `json:"saved"` // liens créés depuis l'abonnement
+}
+
+// SubscriptionEntry remembers an entry already seen, so a link deleted by the user
+// isn't saved again at the next poll
+type SubscriptionEntry struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	SubscriptionID uint   `gorm:"uniqueIndex:idx_subscription_entry"`
+	EntryID        string `gorm:"uniqueIndex:idx_subscription_entry"`
+}
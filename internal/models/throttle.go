@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginThrottle counts failed logins for a key ("email:..." or "ip:...")
+type LoginThrottle struct {
+	ID            uint       `gorm:"primarykey" json:"-"`
+	Key           string     `gorm:"uniqueIndex" json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
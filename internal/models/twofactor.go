@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a one-time code replacing a TOTP code when the device is lost
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint   `gorm:"index"`
+	CodeHash string `gorm:"index"`
+	UsedAt   *time.Time
+}
+
+// TwoFactorChallenge is issued by the login when the password is correct
+// but a TOTP code is still required before creating the session token.
+type TwoFactorChallenge struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	Attempts  int
+	// La session sera ouverte par cookie plutôt que par token
+	CookieSession bool
+}
@@ -1,10 +1,45 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
 	gorm.Model
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	Links    []Link `gorm:"foreignKey:UserID"`
+	Email           string     `json:"email" binding:"required,email"`
+	Password        string     `json:"password" binding:"required,min=6"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Role            string     `gorm:"default:user;index" json:"role"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"` // compte désactivé par un admin
+	Links           []Link     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	// Incrémentée pour révoquer toutes les sessions (changement de mot de passe...)
+	SessionVersion int `json:"-"`
+
+	// Authentification à deux facteurs (TOTP)
+	TOTPSecret       string `json:"-"`
+	TOTPLastStep     int64  `json:"-"` // dernier pas utilisé, empêche le rejeu d'un code
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+}
+
+// IsVerified reports whether the user confirmed their email address
+func (u User) IsVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsAdmin reports whether the user can access the admin API
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsDisabled reports whether an admin disabled the account
+func (u User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerification is a pending confirmation of an email address.
+// Only the hash of the token sent by email is stored.
+type EmailVerification struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	Email     string // address being verified
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles of the workspace members
+const (
+	WorkspaceOwner  = "owner"  // gère les membres et le workspace
+	WorkspaceEditor = "editor" // ajoute, modifie et supprime les liens
+	WorkspaceViewer = "viewer" // lecture seule
+)
+
+// Workspace is a link library shared by its members
+type Workspace struct {
+	gorm.Model
+	Name string `json:"name"`
+}
+
+// WorkspaceMember gives a user a role in a workspace
+type WorkspaceMember struct {
+	gorm.Model
+	WorkspaceID uint   `gorm:"uniqueIndex:idx_workspace_member"`
+	UserID      uint   `gorm:"uniqueIndex:idx_workspace_member;index"`
+	Role        string `gorm:"default:viewer"`
+}
+
+// WorkspaceInvitation is sent by email and accepted by the account with the same address
+type WorkspaceInvitation struct {
+	gorm.Model
+	WorkspaceID uint `gorm:"index"`
+	Email       string
+	Role        string
+	TokenHash   string `gorm:"uniqueIndex"`
+	InvitedByID uint
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,74 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewProvider("vault", "secret")
+	defer idp.Close()
+
+	p := NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "vault",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	})
+	ctx := context.Background()
+
+	verifier := "a-long-random-code-verifier-with-enough-entropy"
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", PKCEChallenge(verifier))
+	assert.NoError(t, err)
+
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+
+	// Le fournisseur approuve et redirige vers le callback avec le code
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	assert.NoError(t, err)
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	assert.Equal(t, "state-1", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+
+	// Un mauvais verifier PKCE est refusé
+	_, err = p.Exchange(ctx, code, "wrong-verifier", "nonce-1")
+	assert.Error(t, err)
+
+	resp, _ = client.Get(authURL)
+	callback, _ = url.Parse(resp.Header.Get("Location"))
+	identity, err := p.Exchange(ctx, callback.Query().Get("code"), verifier, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", identity.Subject)
+	assert.Equal(t, "oidc@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, idp.Issuer(), identity.Issuer)
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	idp := oidctest.NewProvider("vault", "secret")
+	defer idp.Close()
+	ctx := context.Background()
+
+	p := NewProvider(Config{Issuer: idp.Issuer(), ClientID: "vault"})
+	token, err := idp.SignIDToken("nonce-1")
+	assert.NoError(t, err)
+
+	_, err = p.VerifyIDToken(ctx, token, "nonce-1")
+	assert.NoError(t, err)
+
+	_, err = p.VerifyIDToken(ctx, token, "other-nonce")
+	assert.Error(t, err)
+
+	other := NewProvider(Config{Issuer: idp.Issuer(), ClientID: "another-client"})
+	_, err = other.VerifyIDToken(ctx, token, "nonce-1")
+	assert.Error(t, err)
+}
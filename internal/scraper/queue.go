@@ -0,0 +1,61 @@
+package scraper
+
+import "sync"
+
+// Job asks to fetch the metadata of a link
+type Job struct {
+	LinkID uint
+	URL    string
+}
+
+// Queue fetches metadata with a fixed number of workers,
+// so saving or refreshing many links doesn't open as many connections.
+type Queue struct {
+	jobs  chan Job
+	fetch func(url string) (*Metadata, error)
+	done  func(job Job, metadata *Metadata, err error)
+	wg    sync.WaitGroup
+}
+
+// NewQueue starts the workers. done is called for every job, with the error of the fetch if any.
+func NewQueue(workers, size int, done func(job Job, metadata *Metadata, err error)) *Queue {
+	q := &Queue{
+		jobs:  make(chan Job, size),
+		fetch: FetchMetadata,
+		done:  done,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		metadata, err := q.fetch(job.URL)
+		q.done(job, metadata, err)
+	}
+}
+
+// Enqueue adds a job, blocking while the queue is full
+func (q *Queue) Enqueue(job Job) {
+	q.jobs <- job
+}
+
+// TryEnqueue adds a job without blocking and reports whether it was accepted
+func (q *Queue) TryEnqueue(job Job) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting jobs and waits for the pending ones
+func (q *Queue) Close() {
+	close(q.jobs)
+	q.wg.Wait()
+}
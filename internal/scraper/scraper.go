@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -11,6 +12,8 @@ type Metadata struct {
 	Title       string
 	Description string
 	Image       string
+	// URL canonique de la page, absolue, vide si la page n'en annonce pas
+	Canonical string
 }
 
 func FetchMetadata(url string) (*Metadata, error) {
@@ -21,7 +24,7 @@ func FetchMetadata(url string) (*Metadata, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, err
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
@@ -33,9 +36,18 @@ func FetchMetadata(url string) (*Metadata, error) {
 	desc, _ := doc.Find("meta[name='description']").Attr("content")
 	image, _ := doc.Find("meta[property='og:image']").Attr("content")
 
+	var canonical string
+	if href, ok := doc.Find("link[rel='canonical']").Attr("href"); ok {
+		// Le lien canonique peut être relatif à la page, après redirections
+		if ref, err := resp.Request.URL.Parse(strings.TrimSpace(href)); err == nil {
+			canonical = ref.String()
+		}
+	}
+
 	return &Metadata{
 		Title:       title,
 		Description: desc,
 		Image:       image,
+		Canonical:   canonical,
 	}, nil
 }
@@ -3,6 +3,7 @@ package scraper
 import (
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,7 @@ func TestScrapeMetadata(t *testing.T) {
 				<title>Test Page</title>
 				<meta name="description" content="This is a test description.">
 				<meta property="og:image" content="https://example.com/image.jpg">
+				<link rel="canonical" href="/article">
 			</head>
 			<body><p>Hello World!</p></body>
 			</html>`
@@ -32,4 +34,28 @@ func TestScrapeMetadata(t *testing.T) {
 	assert.Equal(t, "Test Page", metadata.Title)
 	assert.Equal(t, "This is a test description.", metadata.Description)
 	assert.Equal(t, "https://example.com/image.jpg", metadata.Image)
+	assert.Equal(t, server.URL+"/article", metadata.Canonical)
+}
+
+func TestQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Page ` + r.URL.Path + `</title></head></html>`))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	titles := map[uint]string{}
+	q := NewQueue(2, 10, func(job Job, metadata *Metadata, err error) {
+		assert.NoError(t, err)
+		mu.Lock()
+		titles[job.LinkID] = metadata.Title
+		mu.Unlock()
+	})
+
+	q.Enqueue(Job{LinkID: 1, URL: server.URL + "/a"})
+	q.Enqueue(Job{LinkID: 2, URL: server.URL + "/b"})
+	assert.True(t, q.TryEnqueue(Job{LinkID: 3, URL: server.URL + "/c"}))
+	q.Close()
+
+	assert.Equal(t, map[uint]string{1: "Page /a", 2: "Page /b", 3: "Page /c"}, titles)
 }
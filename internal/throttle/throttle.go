@@ -0,0 +1,140 @@
+package throttle
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/config"
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/DebroyeAntoine/go_link_vault/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Policy describes how failed logins are slowed down then locked
+type Policy struct {
+	FreeAttempts int           // échecs tolérés avant de ralentir
+	MaxAttempts  int           // échecs avant verrouillage
+	Lockout      time.Duration // durée du verrouillage
+	Window       time.Duration // les compteurs repartent de zéro après cette période sans échec
+}
+
+// AccountPolicy applies to an email address
+func AccountPolicy() Policy {
+	return Policy{
+		FreeAttempts: config.Int("LOGIN_FREE_ATTEMPTS", 3),
+		MaxAttempts:  config.Int("LOGIN_MAX_ATTEMPTS", 10),
+		Lockout:      config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		Window:       config.Duration("LOGIN_ATTEMPT_WINDOW", time.Hour),
+	}
+}
+
+// IPPolicy applies to a client IP, more permissive since several users may share it
+func IPPolicy() Policy {
+	return Policy{
+		FreeAttempts: config.Int("LOGIN_FREE_ATTEMPTS_IP", 10),
+		MaxAttempts:  config.Int("LOGIN_MAX_ATTEMPTS_IP", 50),
+		Lockout:      config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		Window:       config.Duration("LOGIN_ATTEMPT_WINDOW", time.Hour),
+	}
+}
+
+// AccountKey returns the throttle key of an email address
+func AccountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the throttle key of a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// PolicyFor returns the policy matching the kind of key
+func PolicyFor(key string) Policy {
+	if strings.HasPrefix(key, "ip:") {
+		return IPPolicy()
+	}
+	return AccountPolicy()
+}
+
+// Delay returns how long the next attempt must wait after the given number of failures.
+// The delay doubles after each failure past FreeAttempts, and locked is true once MaxAttempts is reached.
+func Delay(failures int, p Policy) (wait time.Duration, locked bool) {
+	if failures >= p.MaxAttempts {
+		return p.Lockout, true
+	}
+	if failures <= p.FreeAttempts {
+		return 0, false
+	}
+
+	// Au-delà, le décalage déborde et donnerait un délai négatif
+	shift := failures - p.FreeAttempts - 1
+	if shift >= 30 {
+		return p.Lockout, false
+	}
+	wait = time.Second << uint(shift)
+	if wait > p.Lockout {
+		wait = p.Lockout
+	}
+	return wait, false
+}
+
+// Blocked returns the longest remaining wait among the keys, 0 if the attempt is allowed
+func Blocked(keys ...string) time.Duration {
+	var throttles []models.LoginThrottle
+	db.DB.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&throttles)
+
+	var wait time.Duration
+	for _, t := range throttles {
+		if remaining := time.Until(*t.LockedUntil); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// RecordFailure counts a failed attempt for each key and returns the keys that got locked out
+func RecordFailure(keys ...string) []string {
+	var lockedKeys []string
+	now := time.Now()
+
+	for _, key := range keys {
+		policy := PolicyFor(key)
+		db.DB.Transaction(func(tx *gorm.DB) error {
+			throttle := models.LoginThrottle{Key: key}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).FirstOrCreate(&throttle).Error; err != nil {
+				return err
+			}
+
+			if now.Sub(throttle.LastFailureAt) > policy.Window {
+				throttle.Failures = 0
+			}
+			throttle.Failures++
+			throttle.LastFailureAt = now
+
+			wait, locked := Delay(throttle.Failures, policy)
+			if wait > 0 {
+				until := now.Add(wait)
+				throttle.LockedUntil = &until
+			}
+			if locked {
+				lockedKeys = append(lockedKeys, key)
+			}
+			return tx.Save(&throttle).Error
+		})
+	}
+	return lockedKeys
+}
+
+// Reset clears the counters of the keys, after a successful login or an admin unlock.
+// It returns the number of keys that were tracked.
+func Reset(keys ...string) int64 {
+	return db.DB.Where("key IN ?", keys).Delete(&models.LoginThrottle{}).RowsAffected
+}
+
+// Locked lists the keys currently blocked, by a lockout or a progressive delay
+func Locked() ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	err := db.DB.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&throttles).Error
+	return throttles, err
+}
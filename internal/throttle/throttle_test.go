@@ -0,0 +1,59 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DebroyeAntoine/go_link_vault/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{FreeAttempts: 3, MaxAttempts: 8, Lockout: 15 * time.Minute}
+
+	wait, locked := Delay(3, p)
+	assert.Zero(t, wait)
+	assert.False(t, locked)
+
+	// Le délai double à chaque échec supplémentaire
+	wait, _ = Delay(4, p)
+	assert.Equal(t, time.Second, wait)
+	wait, _ = Delay(6, p)
+	assert.Equal(t, 4*time.Second, wait)
+
+	wait, locked = Delay(8, p)
+	assert.Equal(t, 15*time.Minute, wait)
+	assert.True(t, locked)
+
+	// Le délai reste plafonné même après de nombreux échecs
+	ip := Policy{FreeAttempts: 10, MaxAttempts: 50, Lockout: 15 * time.Minute}
+	for failures := 11; failures < 50; failures++ {
+		wait, locked = Delay(failures, ip)
+		assert.Positive(t, wait, failures)
+		assert.LessOrEqual(t, wait, 15*time.Minute, failures)
+		assert.False(t, locked)
+	}
+}
+
+func TestRecordFailureLocksKey(t *testing.T) {
+	db.SetupTestDB()
+	t.Setenv("LOGIN_FREE_ATTEMPTS", "5")
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+
+	key := AccountKey("Locked@Example.com")
+	assert.Equal(t, "email:locked@example.com", key)
+
+	assert.Empty(t, RecordFailure(key))
+	assert.Empty(t, RecordFailure(key))
+	assert.Zero(t, Blocked(key))
+
+	assert.Equal(t, []string{key}, RecordFailure(key))
+	assert.Greater(t, Blocked(key, IPKey("127.0.0.1")), 14*time.Minute)
+
+	locked, err := Locked()
+	assert.NoError(t, err)
+	assert.Len(t, locked, 1)
+
+	assert.Equal(t, int64(1), Reset(key))
+	assert.Zero(t, Blocked(key))
+}
@@ -0,0 +1,75 @@
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Paramètres ajoutés par les campagnes et les réseaux sociaux, sans effet sur la page
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"twclid":  true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+	"ref_src": true,
+}
+
+// isTracking reports whether the query parameter only tracks the visitor
+func isTracking(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// Normalize returns the key used to detect duplicate links: http and https are the same page,
+// the host is lowercased without default port, tracking parameters, fragment and trailing
+// slash are removed and the query is sorted. The key is meant for comparisons, not to be
+// opened: the link keeps its URL. Unparsable URLs are returned trimmed.
+func Normalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" {
+		scheme = "https"
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	if u.User != nil {
+		host = u.User.String() + "@" + host
+	}
+
+	normalized := scheme + "://" + host + strings.TrimRight(u.EscapedPath(), "/")
+
+	if u.RawQuery != "" {
+		values, err := url.ParseQuery(u.RawQuery)
+		if err != nil {
+			// Requête non standard (séparateur ;...), gardée telle quelle
+			return normalized + "?" + u.RawQuery
+		}
+		for name := range values {
+			if isTracking(name) {
+				values.Del(name)
+			}
+		}
+		// Encode trie les paramètres par nom
+		if query := values.Encode(); query != "" {
+			normalized += "?" + query
+		}
+	}
+	return normalized
+}
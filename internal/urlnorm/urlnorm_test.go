@@ -0,0 +1,26 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"https://go.dev/blog/":                          "https://go.dev/blog",
+		"http://Go.Dev/blog":                            "https://go.dev/blog",
+		"https://go.dev:443/blog#intro":                 "https://go.dev/blog",
+		"https://GO.dev":                                "https://go.dev",
+		"https://go.dev/":                               "https://go.dev",
+		"http://localhost:8080/a/":                      "https://localhost:8080/a",
+		"https://go.dev/doc?utm_source=x&b=2&a=1":       "https://go.dev/doc?a=1&b=2",
+		"https://go.dev/doc?fbclid=abc&UTM_Medium=mail": "https://go.dev/doc",
+		"https://go.dev/Case/Sensitive":                 "https://go.dev/Case/Sensitive",
+		"https://go.dev/doc?x=1;y=2":                    "https://go.dev/doc?x=1;y=2",
+		"  https://go.dev/search?q=a+b  ":               "https://go.dev/search?q=a+b",
+		"not a url":                                     "not a url",
+	} {
+		assert.Equal(t, expected, Normalize(raw), raw)
+	}
+}